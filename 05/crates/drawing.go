@@ -0,0 +1,199 @@
+package crates
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DrawingError reports a problem at a specific position in a drawing.
+// Line and Column are both 1-based.
+type DrawingError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *DrawingError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+func drawingErr(line, col int, format string, a ...any) error {
+	return &DrawingError{
+		Line:   line,
+		Column: col,
+		Msg:    fmt.Sprintf(format, a...),
+	}
+}
+
+// span is a half-open byte range [start, end) within a line.
+type span struct {
+	start, end int
+}
+
+func (s span) overlaps(o span) bool {
+	return s.start < o.end && o.start < s.end
+}
+
+// ParseDrawing parses a drawing of crate stacks, the last line being
+// the stack label row. The positions of the labels decide which stack
+// a crate belongs to, so labels can be any number of digits wide and
+// crates can have names of any length, as long as each crate name sits
+// above exactly one label. Labels must be numbered 1, 2, 3 and so on.
+//
+// The first line of the drawing is treated as line 1 when reporting
+// errors.
+func ParseDrawing(lines []string) ([]*Stack, error) {
+	if len(lines) == 0 {
+		return nil, drawingErr(1, 1, "empty drawing")
+	}
+
+	labelLine := len(lines)
+
+	labels, err := parseLabels(lines[labelLine-1], labelLine)
+	if err != nil {
+		return nil, err
+	}
+
+	// Crates are collected top to bottom, as they are drawn.
+	piles := make([][]string, len(labels))
+
+	for i, line := range lines[:labelLine-1] {
+		linum := i + 1
+
+		row, err := parseCrateRow(line, linum, labels)
+		if err != nil {
+			return nil, err
+		}
+
+		for s, name := range row {
+			if name != "" {
+				piles[s] = append(piles[s], name)
+				continue
+			}
+
+			// Crates can't float, once we have seen a crate in
+			// a stack every row below it must have one as well.
+			if len(piles[s]) > 0 {
+				return nil, drawingErr(linum, labels[s].start+1,
+					"missing crate under [%s] in stack %d",
+					piles[s][len(piles[s])-1], s+1)
+			}
+		}
+	}
+
+	stacks := make([]*Stack, len(piles))
+
+	for i, pile := range piles {
+		var stack Stack
+
+		for j := range pile {
+			stack.Push(pile[len(pile)-1-j])
+		}
+
+		stacks[i] = &stack
+	}
+
+	return stacks, nil
+}
+
+func parseLabels(line string, linum int) ([]span, error) {
+	var labels []span
+
+	for i := 0; i < len(line); {
+		if line[i] == ' ' {
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(line) && line[end] != ' ' {
+			end++
+		}
+
+		n, err := strconv.Atoi(line[i:end])
+		if err != nil {
+			return nil, drawingErr(linum, i+1,
+				"invalid stack label %q", line[i:end])
+		}
+
+		if n != len(labels)+1 {
+			return nil, drawingErr(linum, i+1,
+				"expected stack label %d, got %d",
+				len(labels)+1, n)
+		}
+
+		labels = append(labels, span{start: i, end: end})
+		i = end
+	}
+
+	if len(labels) == 0 {
+		return nil, drawingErr(linum, 1, "missing stack labels")
+	}
+
+	return labels, nil
+}
+
+// parseCrateRow returns the crate names in a row indexed by stack, with
+// an empty string for the stacks that have no crate on this row.
+func parseCrateRow(line string, linum int, labels []span) ([]string, error) {
+	row := make([]string, len(labels))
+
+	for i := 0; i < len(line); {
+		if line[i] == ' ' {
+			i++
+			continue
+		}
+
+		if line[i] != '[' {
+			return nil, drawingErr(linum, i+1,
+				"unexpected %q outside of crate", line[i])
+		}
+
+		end := strings.IndexByte(line[i:], ']')
+		if end == -1 {
+			return nil, drawingErr(linum, i+1, "unterminated crate")
+		}
+
+		end += i
+
+		name := line[i+1 : end]
+		if name == "" || strings.ContainsAny(name, " [") {
+			return nil, drawingErr(linum, i+1,
+				"invalid crate name %q", name)
+		}
+
+		stack := -1
+		nameSpan := span{start: i + 1, end: end}
+
+		for s, label := range labels {
+			if !nameSpan.overlaps(label) {
+				continue
+			}
+
+			if stack != -1 {
+				return nil, drawingErr(linum, i+1,
+					"crate [%s] spans stacks %d and %d",
+					name, stack+1, s+1)
+			}
+
+			stack = s
+		}
+
+		if stack == -1 {
+			return nil, drawingErr(linum, i+1,
+				"crate [%s] is not above a stack label", name)
+		}
+
+		if row[stack] != "" {
+			return nil, drawingErr(linum, i+1,
+				"crate [%s] overlaps [%s] in stack %d",
+				name, row[stack], stack+1)
+		}
+
+		row[stack] = name
+		i = end + 1
+	}
+
+	return row, nil
+}
@@ -0,0 +1,145 @@
+package crates
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseDrawing(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		lines  []string
+		stacks []string
+	}{
+		{
+			name: "puzzle example",
+			lines: []string{
+				"    [D]    ",
+				"[N] [C]    ",
+				"[Z] [M] [P]",
+				" 1   2   3 ",
+			},
+			stacks: []string{"[Z] [N]", "[M] [C] [D]", "[P]"},
+		},
+		{
+			name: "multi-digit labels",
+			lines: []string{
+				"                                    [J]",
+				"[A] [B] [C] [D] [E] [F] [G] [H] [I] [K]",
+				" 1   2   3   4   5   6   7   8   9  10",
+			},
+			stacks: []string{
+				"[A]", "[B]", "[C]", "[D]", "[E]",
+				"[F]", "[G]", "[H]", "[I]", "[K] [J]",
+			},
+		},
+		{
+			name: "wide crate names",
+			lines: []string{
+				"       [XYZ]",
+				"[AB]  [CDEF]",
+				"  1     2",
+			},
+			stacks: []string{"[AB]", "[CDEF] [XYZ]"},
+		},
+		{
+			name: "empty stack",
+			lines: []string{
+				"        [C]",
+				"[A]     [B]",
+				" 1   2   3",
+			},
+			stacks: []string{"[A]", "", "[B] [C]"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stacks, err := ParseDrawing(tc.lines)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, len(stacks))
+
+			for i, s := range stacks {
+				got[i] = s.String()
+			}
+
+			if !reflect.DeepEqual(got, tc.stacks) {
+				t.Errorf("stacks are %q, want %q", got, tc.stacks)
+			}
+		})
+	}
+}
+
+func TestParseDrawingErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		lines []string
+		err   DrawingError
+	}{
+		{
+			name:  "empty drawing",
+			lines: nil,
+			err:   DrawingError{Line: 1, Column: 1, Msg: "empty drawing"},
+		},
+		{
+			name:  "no labels",
+			lines: []string{"[A]", "   "},
+			err:   DrawingError{Line: 2, Column: 1, Msg: "missing stack labels"},
+		},
+		{
+			name:  "bad label order",
+			lines: []string{"[A] [B]", " 1   3"},
+			err:   DrawingError{Line: 2, Column: 6, Msg: "expected stack label 2, got 3"},
+		},
+		{
+			name:  "invalid label",
+			lines: []string{"[A]", " a"},
+			err:   DrawingError{Line: 2, Column: 2, Msg: `invalid stack label "a"`},
+		},
+		{
+			name:  "crate spanning two labels",
+			lines: []string{"[LONG]", " 1  2"},
+			err:   DrawingError{Line: 1, Column: 1, Msg: "crate [LONG] spans stacks 1 and 2"},
+		},
+		{
+			name:  "crate between labels",
+			lines: []string{"    [A]", " 1      2"},
+			err:   DrawingError{Line: 1, Column: 5, Msg: "crate [A] is not above a stack label"},
+		},
+		{
+			name:  "floating crate",
+			lines: []string{"[A] [B]", "    [C]", " 1   2"},
+			err:   DrawingError{Line: 2, Column: 2, Msg: "missing crate under [A] in stack 1"},
+		},
+		{
+			name:  "overlapping crates",
+			lines: []string{"[A][B]", " 1"},
+			err:   DrawingError{Line: 1, Column: 4, Msg: "crate [B] is not above a stack label"},
+		},
+		{
+			name:  "unterminated crate",
+			lines: []string{"[A", " 1"},
+			err:   DrawingError{Line: 1, Column: 1, Msg: "unterminated crate"},
+		},
+		{
+			name:  "text outside crate",
+			lines: []string{" A ", " 1"},
+			err:   DrawingError{Line: 1, Column: 2, Msg: `unexpected 'A' outside of crate`},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseDrawing(tc.lines)
+
+			var de *DrawingError
+			if !errors.As(err, &de) {
+				t.Fatalf("expected a *DrawingError, got %v", err)
+			}
+
+			if *de != tc.err {
+				t.Errorf("error is %+v, want %+v", *de, tc.err)
+			}
+		})
+	}
+}
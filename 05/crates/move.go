@@ -0,0 +1,168 @@
+package crates
+
+import "fmt"
+
+// Move is a single step of a rearrangement procedure. From and To are
+// 1-based stack numbers, as written in the procedure.
+type Move struct {
+	Count int
+	From  int
+	To    int
+}
+
+func (m Move) String() string {
+	return fmt.Sprintf("move %d from %d to %d", m.Count, m.From, m.To)
+}
+
+// ParseMove parses a "move N from A to B" instruction.
+func ParseMove(line []byte) (Move, error) {
+	var m Move
+
+	p := moveParser{line: line}
+
+	p.literal("move ")
+	m.Count = p.number()
+	p.literal(" from ")
+	m.From = p.number()
+	p.literal(" to ")
+	m.To = p.number()
+
+	if p.err == nil && p.pos != len(line) {
+		p.fail("unexpected %q", line[p.pos:])
+	}
+
+	return m, p.err
+}
+
+type moveParser struct {
+	line []byte
+	pos  int
+	err  error
+}
+
+func (p *moveParser) fail(format string, a ...any) {
+	p.err = fmt.Errorf("column %d: %s", p.pos+1, fmt.Sprintf(format, a...))
+}
+
+func (p *moveParser) literal(s string) {
+	if p.err != nil {
+		return
+	}
+
+	if len(p.line)-p.pos < len(s) || string(p.line[p.pos:p.pos+len(s)]) != s {
+		p.fail("expected %q", s)
+		return
+	}
+
+	p.pos += len(s)
+}
+
+func (p *moveParser) number() int {
+	if p.err != nil {
+		return 0
+	}
+
+	var n, digits int
+
+	for ; p.pos < len(p.line); p.pos++ {
+		c := p.line[p.pos]
+		if c < '0' || c > '9' {
+			break
+		}
+
+		if n > (maxInt-9)/10 {
+			p.fail("number too large")
+			return 0
+		}
+
+		n = n*10 + int(c-'0')
+		digits++
+	}
+
+	if digits == 0 {
+		p.fail("expected a number")
+		return 0
+	}
+
+	return n
+}
+
+const maxInt = int(^uint(0) >> 1)
+
+// Check verifies that the move refers to stacks that exist when there
+// are numStacks stacks.
+func (m Move) Check(numStacks int) error {
+	if m.From < 1 || m.From > numStacks {
+		return fmt.Errorf("unknown source stack %d", m.From)
+	}
+
+	if m.To < 1 || m.To > numStacks {
+		return fmt.Errorf("unknown destination stack %d", m.To)
+	}
+
+	return nil
+}
+
+// Move9000 moves n crates from src to dst the way the CrateMover 9000
+// does, one at a time, which leaves them in reverse order on dst. The
+// crates are moved with a single reversed copy rather than n pops and
+// pushes. False is returned, and nothing is moved, if src holds fewer
+// than n crates.
+func Move9000(src, dst *Stack, n int) bool {
+	top := len(src.crates)
+	if top < n {
+		return false
+	}
+
+	// Moving crates one at a time onto the same stack puts every
+	// crate right back where it was.
+	if src == dst {
+		return true
+	}
+
+	moved := src.crates[top-n:]
+	tail := dst.grow(n)
+
+	for i, c := range moved {
+		tail[n-1-i] = c
+	}
+
+	src.crates = src.crates[:top-n]
+
+	return true
+}
+
+// Move9001 moves n crates from src to dst the way the CrateMover 9001
+// does, all at once, which retains their order. False is returned, and
+// nothing is moved, if src holds fewer than n crates.
+func Move9001(src, dst *Stack, n int) bool {
+	top := len(src.crates)
+	if top < n {
+		return false
+	}
+
+	if src == dst {
+		return true
+	}
+
+	copy(dst.grow(n), src.crates[top-n:])
+
+	src.crates = src.crates[:top-n]
+
+	return true
+}
+
+// grow extends the stack by n slots and returns them.
+func (s *Stack) grow(n int) []string {
+	l := len(s.crates)
+
+	if cap(s.crates)-l < n {
+		c := make([]string, l, 2*l+n)
+		copy(c, s.crates)
+		s.crates = c
+	}
+
+	s.crates = s.crates[:l+n]
+
+	return s.crates[l:]
+}
@@ -0,0 +1,187 @@
+package crates
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestParseMove(t *testing.T) {
+	for _, tc := range []struct {
+		line string
+		move Move
+		err  string
+	}{
+		{line: "move 1 from 2 to 1", move: Move{Count: 1, From: 2, To: 1}},
+		{line: "move 130 from 10 to 11", move: Move{Count: 130, From: 10, To: 11}},
+		{line: "", err: `column 1: expected "move "`},
+		{line: "mvoe 1 from 2 to 1", err: `column 1: expected "move "`},
+		{line: "move x from 2 to 1", err: "column 6: expected a number"},
+		{line: "move 1 form 2 to 1", err: `column 7: expected " from "`},
+		{line: "move 1 from  2 to 1", err: "column 13: expected a number"},
+		{line: "move 1 from 2 to", err: `column 14: expected " to "`},
+		{line: "move 1 from 2 to ", err: "column 18: expected a number"},
+		{line: "move 1 from 2 to 1 ", err: `column 19: unexpected " "`},
+		{line: "move 1 from 2 to 1x", err: `column 19: unexpected "x"`},
+		{
+			line: "move 99999999999999999999 from 1 to 2",
+			err:  "column 24: number too large",
+		},
+	} {
+		m, err := ParseMove([]byte(tc.line))
+
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("%q: unexpected error %v", tc.line, err)
+		case tc.err != "" && err == nil:
+			t.Errorf("%q: parsed as %v, want error %q", tc.line, m, tc.err)
+		case tc.err != "" && err.Error() != tc.err:
+			t.Errorf("%q: error is %q, want %q", tc.line, err, tc.err)
+		case tc.err == "" && m != tc.move:
+			t.Errorf("%q: parsed as %v, want %v", tc.line, m, tc.move)
+		}
+	}
+}
+
+func TestMoveCheck(t *testing.T) {
+	for _, tc := range []struct {
+		move Move
+		err  string
+	}{
+		{move: Move{Count: 1, From: 1, To: 3}},
+		{move: Move{Count: 1, From: 0, To: 3}, err: "unknown source stack 0"},
+		{move: Move{Count: 1, From: 4, To: 3}, err: "unknown source stack 4"},
+		{move: Move{Count: 1, From: 1, To: 4}, err: "unknown destination stack 4"},
+	} {
+		err := tc.move.Check(3)
+
+		if tc.err == "" && err != nil || tc.err != "" && (err == nil || err.Error() != tc.err) {
+			t.Errorf("%v: error is %v, want %q", tc.move, err, tc.err)
+		}
+	}
+}
+
+// benchMoves is a procedure that moves crates back and forth between
+// nine stacks without ever running out.
+func benchMoves(n, height, maxCount int) []Move {
+	rnd := rand.New(rand.NewSource(1))
+
+	heights := make([]int, 9)
+	for i := range heights {
+		heights[i] = height
+	}
+
+	moves := make([]Move, n)
+
+	for i := range moves {
+		src := rnd.Intn(len(heights))
+		for heights[src] == 0 {
+			src = rnd.Intn(len(heights))
+		}
+
+		dst := rnd.Intn(len(heights) - 1)
+		if dst >= src {
+			dst++
+		}
+
+		limit := maxCount
+		if heights[src] < limit {
+			limit = heights[src]
+		}
+
+		count := 1 + rnd.Intn(limit)
+
+		heights[src] -= count
+		heights[dst] += count
+
+		moves[i] = Move{Count: count, From: src + 1, To: dst + 1}
+	}
+
+	return moves
+}
+
+func benchStacks(height int) []*Stack {
+	stacks := make([]*Stack, 9)
+
+	for i := range stacks {
+		var s Stack
+
+		for j := 0; j < height; j++ {
+			s.Push(string(rune('A' + j%26)))
+		}
+
+		stacks[i] = &s
+	}
+
+	return stacks
+}
+
+// The benchmarks move crates between nine stacks of benchHeight crates.
+const (
+	benchHeight   = 10000
+	benchMaxCount = 1000
+	benchNumMoves = 100000
+)
+
+func benchmarkMove(b *testing.B, move func(src, dst *Stack, n int) bool) {
+	moves := benchMoves(benchNumMoves, benchHeight, benchMaxCount)
+
+	var crates int
+
+	for _, m := range moves {
+		crates += m.Count
+	}
+
+	// Rebuilding the stacks isn't timed, so the throughput is timed
+	// separately from the benchmark timer.
+	var elapsed time.Duration
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		stacks := benchStacks(benchHeight)
+		b.StartTimer()
+
+		start := time.Now()
+
+		for _, m := range moves {
+			if !move(stacks[m.From-1], stacks[m.To-1], m.Count) {
+				b.Fatalf("not enough crates for %s", m)
+			}
+		}
+
+		elapsed += time.Since(start)
+	}
+
+	b.ReportMetric(float64(len(moves)*b.N)/elapsed.Seconds(), "moves/s")
+	b.ReportMetric(float64(crates*b.N)/elapsed.Seconds(), "crates/s")
+}
+
+func BenchmarkMove9000(b *testing.B) {
+	benchmarkMove(b, Move9000)
+}
+
+func BenchmarkMove9001(b *testing.B) {
+	benchmarkMove(b, Move9001)
+}
+
+func BenchmarkParseMove(b *testing.B) {
+	moves := benchMoves(benchNumMoves, benchHeight, benchMaxCount)
+
+	var procedure [][]byte
+
+	for _, m := range moves {
+		procedure = append(procedure, []byte(m.String()))
+	}
+
+	start := time.Now()
+
+	for i := 0; i < b.N; i++ {
+		for _, line := range procedure {
+			if _, err := ParseMove(line); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.ReportMetric(float64(len(moves)*b.N)/time.Since(start).Seconds(), "moves/s")
+}
@@ -0,0 +1,40 @@
+package crates
+
+import "strings"
+
+// Stack is a pile of named crates, bottom crate first.
+type Stack struct {
+	crates []string
+}
+
+func (s *Stack) Push(c ...string) {
+	s.crates = append(s.crates, c...)
+}
+
+func (s *Stack) Peek() string {
+	if len(s.crates) == 0 {
+		return ""
+	}
+
+	return s.crates[len(s.crates)-1]
+}
+
+func (s *Stack) Len() int {
+	return len(s.crates)
+}
+
+func (s *Stack) String() string {
+	var b strings.Builder
+
+	for i, c := range s.crates {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+
+		b.WriteByte('[')
+		b.WriteString(c)
+		b.WriteByte(']')
+	}
+
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package crates
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Problem is an invalid instruction found by a Validator.
+type Problem struct {
+	Line int
+	Err  error
+}
+
+func (p Problem) Error() string {
+	return fmt.Sprintf("line %d: %v", p.Line, p.Err)
+}
+
+// Validator checks every instruction of a procedure without moving any
+// crates, by keeping track of the number of crates in each stack.
+//
+// The stack heights can't be trusted once a move has asked for more
+// crates than its source stack holds, so by default the validator stops
+// tracking them at the first such move, and only checks the syntax and
+// the stack numbers of the instructions that follow. Other problems
+// don't move any crates, so the heights are still tracked after them.
+// With KeepGoing set moves with too few crates are skipped instead, and
+// the heights are kept up to date with the valid ones.
+type Validator struct {
+	KeepGoing bool
+	Problems  []Problem
+
+	heights  []int
+	tracking bool
+}
+
+func NewValidator(stacks []*Stack) *Validator {
+	heights := make([]int, len(stacks))
+
+	for i, s := range stacks {
+		heights[i] = s.Len()
+	}
+
+	return &Validator{
+		heights:  heights,
+		tracking: true,
+	}
+}
+
+// Check validates the instruction on the given line and records any
+// problem with it. It returns false if the instruction is invalid.
+func (v *Validator) Check(linum int, line []byte) bool {
+	tooFew, err := v.check(line)
+	if err == nil {
+		return true
+	}
+
+	v.Problems = append(v.Problems, Problem{
+		Line: linum,
+		Err:  err,
+	})
+
+	if tooFew && !v.KeepGoing {
+		v.tracking = false
+	}
+
+	return false
+}
+
+// Run checks every instruction read by s, counting lines from the one
+// after linum, and writes the problems it finds to w. An error is
+// returned if the procedure can't be read or has invalid instructions.
+func (v *Validator) Run(s *bufio.Scanner, linum int, w io.Writer) error {
+	for s.Scan() {
+		linum++
+
+		v.Check(linum, s.Bytes())
+	}
+
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("failed to read procedure: %w", err)
+	}
+
+	for _, p := range v.Problems {
+		fmt.Fprintln(w, p.Error())
+	}
+
+	if len(v.Problems) > 0 {
+		return fmt.Errorf("found %d invalid instructions",
+			len(v.Problems))
+	}
+
+	return nil
+}
+
+// check validates a single instruction. tooFew is set if the move needs
+// more crates than its source stack holds, which leaves the heights in
+// doubt.
+func (v *Validator) check(line []byte) (tooFew bool, err error) {
+	m, err := ParseMove(line)
+	if err != nil {
+		return false, fmt.Errorf("malformed instruction %q: %w", line, err)
+	}
+
+	if err := m.Check(len(v.heights)); err != nil {
+		return false, err
+	}
+
+	if m.From == m.To {
+		return false, fmt.Errorf("source and destination are both stack %d",
+			m.From)
+	}
+
+	if !v.tracking {
+		return false, nil
+	}
+
+	src, dst := &v.heights[m.From-1], &v.heights[m.To-1]
+
+	if *src < m.Count {
+		return true, fmt.Errorf("cannot move %d crates from stack %d, it has %d",
+			m.Count, m.From, *src)
+	}
+
+	*src -= m.Count
+	*dst += m.Count
+
+	return false, nil
+}
@@ -0,0 +1,58 @@
+package crates
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	procedure := strings.Join([]string{
+		"move 1 from 1 to 12",
+		"move x",
+		"move 1 from 2 to 2",
+		"move 100 from 1 to 2",
+		"move 1 from 3 to 1",
+		"move 100 from 2 to 3",
+	}, "\n")
+
+	for _, tc := range []struct {
+		name      string
+		keepGoing bool
+		lines     []int
+	}{
+		// Problems that move no crates keep the heights tracked,
+		// the first move with too few crates stops the tracking.
+		{name: "default", lines: []int{1, 2, 3, 4}},
+		{name: "keep going", keepGoing: true, lines: []int{1, 2, 3, 4, 6}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stacks := make([]*Stack, 3)
+
+			for i := range stacks {
+				stacks[i] = &Stack{}
+				stacks[i].Push("A", "B")
+			}
+
+			v := NewValidator(stacks)
+			v.KeepGoing = tc.keepGoing
+
+			err := v.Run(bufio.NewScanner(strings.NewReader(procedure)), 0, io.Discard)
+			if err == nil {
+				t.Fatal("expected the procedure to be invalid")
+			}
+
+			var lines []int
+
+			for _, p := range v.Problems {
+				lines = append(lines, p.Line)
+			}
+
+			if !reflect.DeepEqual(lines, tc.lines) {
+				t.Errorf("problems on lines %v, want %v", lines, tc.lines)
+			}
+		})
+	}
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"bufio"
-	"bytes"
+	"flag"
 	"fmt"
 	"os"
+
+	"github.com/hugowetterberg/advent2022/05/crates"
 )
 
 func main() {
@@ -14,132 +16,98 @@ func main() {
 	}
 }
 
-type RuneStack struct {
-	top   int
-	runes []rune
-}
-
-func (rs *RuneStack) Push(r rune) {
-	rs.runes = append(rs.runes, r)
-	rs.top++
-}
-
-func (rs *RuneStack) Pop() rune {
-	if rs.top == 0 {
-		return 0
-	}
-
-	rs.top--
-
-	r := rs.runes[rs.top]
-
-	rs.runes = rs.runes[0:rs.top]
-
-	return r
-}
-
-func (rs *RuneStack) Peek() rune {
-	if rs.top == 0 {
-		return 0
-	}
-
-	return rs.runes[rs.top-1]
-}
-
-func (rs *RuneStack) String() string {
-	return string(rs.runes)
-}
-
 func run() error {
-	var linum int
+	var (
+		linum                        int
+		verbose, validate, keepGoing bool
+	)
 
-	var pileInput [][]rune
+	flag.BoolVar(&verbose, "v", false,
+		"print the stacks after every crate that is moved")
+	flag.BoolVar(&validate, "validate", false,
+		"check the whole procedure and report every invalid instruction")
+	flag.BoolVar(&keepGoing, "keep-going", false,
+		"keep tracking stack heights past invalid instructions when validating")
+	flag.Parse()
+
+	var drawing []string
 
 	r := bufio.NewScanner(os.Stdin)
 
 	for r.Scan() {
-		line := r.Bytes()
+		line := r.Text()
 		linum++
 
 		if len(line) == 0 {
 			break
 		}
 
-		for i := 0; i*4 < len(line); i++ {
-			if i == len(pileInput) {
-				pileInput = append(pileInput, []rune{})
-			}
-
-			offset := i * 4
-
-			pile := pileInput[i]
-
-			if line[offset] == '[' {
-				pile = append(pile, rune(line[offset+1]))
-			}
-
-			pileInput[i] = pile
-		}
+		drawing = append(drawing, line)
 	}
 
-	var runeStacks []*RuneStack
-
-	for _, runes := range pileInput {
-		var stack RuneStack
-
-		for i := range runes {
-			stack.Push(runes[len(runes)-1-i])
-		}
+	stacks, err := crates.ParseDrawing(drawing)
+	if err != nil {
+		return fmt.Errorf("invalid drawing: %w", err)
+	}
 
-		runeStacks = append(runeStacks, &stack)
+	if validate {
+		return validateProcedure(r, linum, stacks, keepGoing)
 	}
 
-	printStacks(runeStacks)
+	if verbose {
+		printStacks(stacks)
+	}
 
 	for r.Scan() {
 		line := r.Bytes()
 		linum++
 
-		r := bytes.NewReader(line)
-
-		var count, source, dst int
-
-		_, err := fmt.Fscanf(r, "move %d from %d to %d",
-			&count, &source, &dst)
+		m, err := crates.ParseMove(line)
 		if err != nil {
 			return fmt.Errorf("failed to parse line %d: %v",
 				linum, err)
 		}
 
-		fmt.Fprintln(os.Stdout, string(line))
+		if err := m.Check(len(stacks)); err != nil {
+			return fmt.Errorf("invalid move on line %d: %v",
+				linum, err)
+		}
 
-		for i := 0; i < count; i++ {
-			crate := runeStacks[source-1].Pop()
-			if crate == 0 {
-				return fmt.Errorf("no more crates in stack %d",
-					source)
+		src, dst := stacks[m.From-1], stacks[m.To-1]
+
+		if !verbose {
+			if !crates.Move9000(src, dst, m.Count) {
+				return fmt.Errorf("not enough crates in stack %d on line %d",
+					m.From, linum)
 			}
 
-			runeStacks[dst-1].Push(crate)
+			continue
+		}
 
-			printStacks(runeStacks)
+		fmt.Fprintln(os.Stdout, string(line))
 
+		for i := 0; i < m.Count; i++ {
+			if !crates.Move9000(src, dst, 1) {
+				return fmt.Errorf("no more crates in stack %d",
+					m.From)
+			}
+
+			printStacks(stacks)
 		}
 	}
 
-	err := r.Err()
-	if err != nil {
+	if err := r.Err(); err != nil {
 		return fmt.Errorf("failed to read from stdin: %w", err)
 	}
 
-	for i, stack := range runeStacks {
+	for i, stack := range stacks {
 		crate := stack.Peek()
-		if crate == 0 {
+		if crate == "" {
 			println(i, "is empty")
 			continue
 		}
 
-		print(string(crate))
+		print(crate)
 	}
 
 	println()
@@ -147,8 +115,23 @@ func run() error {
 	return nil
 }
 
-func printStacks(s []*RuneStack) {
+func printStacks(s []*crates.Stack) {
 	for i, stack := range s {
 		fmt.Fprintf(os.Stdout, "%d: %s\n", i, stack.String())
 	}
 }
+
+func validateProcedure(
+	r *bufio.Scanner, linum int, stacks []*crates.Stack, keepGoing bool,
+) error {
+	v := crates.NewValidator(stacks)
+	v.KeepGoing = keepGoing
+
+	if err := v.Run(r, linum, os.Stdout); err != nil {
+		return err
+	}
+
+	println("procedure is valid")
+
+	return nil
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"bufio"
-	"bytes"
+	"flag"
 	"fmt"
 	"os"
+
+	"github.com/hugowetterberg/advent2022/05/crates"
 )
 
 func main() {
@@ -14,128 +16,80 @@ func main() {
 	}
 }
 
-type RuneStack struct {
-	top   int
-	runes []rune
-}
-
-func (rs *RuneStack) Push(r ...rune) {
-	rs.runes = append(rs.runes, r...)
-	rs.top += len(r)
-}
-
-func (rs *RuneStack) Pop(n int) []rune {
-	if rs.top == 0 || rs.top < n {
-		return nil
-	}
-
-	rs.top -= n
-
-	r := rs.runes[rs.top:]
-
-	rs.runes = rs.runes[0:rs.top]
-
-	return r
-}
-
-func (rs *RuneStack) Peek() rune {
-	if rs.top == 0 {
-		return 0
-	}
-
-	return rs.runes[rs.top-1]
-}
-
-func (rs *RuneStack) String() string {
-	return string(rs.runes)
-}
-
 func run() error {
-	var linum int
+	var (
+		linum               int
+		validate, keepGoing bool
+	)
+
+	flag.BoolVar(&validate, "validate", false,
+		"check the whole procedure and report every invalid instruction")
+	flag.BoolVar(&keepGoing, "keep-going", false,
+		"keep tracking stack heights past invalid instructions when validating")
+	flag.Parse()
 
-	var pileInput [][]rune
+	var drawing []string
 
 	r := bufio.NewScanner(os.Stdin)
 
 	for r.Scan() {
-		line := r.Bytes()
+		line := r.Text()
 		linum++
 
 		if len(line) == 0 {
 			break
 		}
 
-		for i := 0; i*4 < len(line); i++ {
-			if i == len(pileInput) {
-				pileInput = append(pileInput, []rune{})
-			}
-
-			offset := i * 4
-
-			pile := pileInput[i]
-
-			if line[offset] == '[' {
-				pile = append(pile, rune(line[offset+1]))
-			}
-
-			pileInput[i] = pile
-		}
+		drawing = append(drawing, line)
 	}
 
-	var runeStacks []*RuneStack
-
-	for _, runes := range pileInput {
-		var stack RuneStack
-
-		for i := range runes {
-			stack.Push(runes[len(runes)-1-i])
-		}
+	stacks, err := crates.ParseDrawing(drawing)
+	if err != nil {
+		return fmt.Errorf("invalid drawing: %w", err)
+	}
 
-		runeStacks = append(runeStacks, &stack)
+	if validate {
+		return validateProcedure(r, linum, stacks, keepGoing)
 	}
 
-	printStacks(runeStacks)
+	printStacks(stacks)
 
 	for r.Scan() {
 		line := r.Bytes()
 		linum++
 
-		r := bytes.NewReader(line)
-
-		var count, source, dst int
-
-		_, err := fmt.Fscanf(r, "move %d from %d to %d",
-			&count, &source, &dst)
+		m, err := crates.ParseMove(line)
 		if err != nil {
 			return fmt.Errorf("failed to parse line %d: %v",
 				linum, err)
 		}
 
-		crates := runeStacks[source-1].Pop(count)
-		if crates == nil {
-			return fmt.Errorf("not enough crates in stack %d",
-				source)
+		if err := m.Check(len(stacks)); err != nil {
+			return fmt.Errorf("invalid move on line %d: %v",
+				linum, err)
 		}
 
-		runeStacks[dst-1].Push(crates...)
+		if !crates.Move9001(stacks[m.From-1], stacks[m.To-1], m.Count) {
+			return fmt.Errorf("not enough crates in stack %d",
+				m.From)
+		}
 	}
 
-	err := r.Err()
-	if err != nil {
+	if err := r.Err(); err != nil {
 		return fmt.Errorf("failed to read from stdin: %w", err)
 	}
 
 	println("Final arrangement")
-	printStacks(runeStacks)
+	printStacks(stacks)
 
-	for i, stack := range runeStacks {
+	for i, stack := range stacks {
 		crate := stack.Peek()
-		if crate == 0 {
+		if crate == "" {
 			println(i, "is empty")
 			continue
 		}
 
-		print(string(crate))
+		print(crate)
 	}
 
 	println()
@@ -143,8 +97,23 @@ func run() error {
 	return nil
 }
 
-func printStacks(s []*RuneStack) {
+func printStacks(s []*crates.Stack) {
 	for i, stack := range s {
 		fmt.Fprintf(os.Stdout, "%d: %s\n", i+1, stack.String())
 	}
 }
+
+func validateProcedure(
+	r *bufio.Scanner, linum int, stacks []*crates.Stack, keepGoing bool,
+) error {
+	v := crates.NewValidator(stacks)
+	v.KeepGoing = keepGoing
+
+	if err := v.Run(r, linum, os.Stdout); err != nil {
+		return err
+	}
+
+	println("procedure is valid")
+
+	return nil
+}
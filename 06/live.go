@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hugowetterberg/advent2022/06/marker"
+)
+
+// liveConfig configures the decoding of live streams.
+type liveConfig struct {
+	Size    int
+	Framing marker.Framing
+}
+
+// eventLog serialises the events of concurrent streams to one writer.
+type eventLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *eventLog) Printf(format string, a ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fmt.Fprintf(l.w, format, a...)
+}
+
+// parseListenAddr splits a listen address like "tcp://:9000" or
+// "unix:///tmp/comm.sock" into a network and an address.
+func parseListenAddr(s string) (string, string, error) {
+	network, address, ok := strings.Cut(s, "://")
+	if !ok {
+		return "", "", fmt.Errorf(
+			"missing network in listen address %q", s)
+	}
+
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+	default:
+		return "", "", fmt.Errorf("unsupported network %q", network)
+	}
+
+	return network, address, nil
+}
+
+// serve decodes every connection accepted by l until l is closed.
+func serve(l net.Listener, log *eventLog, conf liveConfig) error {
+	var wg sync.WaitGroup
+
+	defer wg.Wait()
+
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+
+			name := conn.RemoteAddr().String()
+			if name == "" || name == "@" {
+				name = "conn"
+			}
+
+			err := decodeStream(conn, name, log, conf)
+			if err != nil {
+				log.Printf("%s: %v\n", name, err)
+			}
+		}()
+	}
+}
+
+// followFIFO decodes the named pipe at path, reopening it for the next
+// writer every time the previous one has closed it. Regular files are
+// only decoded once.
+func followFIFO(path string, log *eventLog, conf liveConfig) error {
+	for {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %q: %w", path, err)
+		}
+
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+
+			return fmt.Errorf("failed to stat %q: %w", path, err)
+		}
+
+		err = decodeStream(f, path, log, conf)
+
+		f.Close()
+
+		if err != nil {
+			return err
+		}
+
+		if info.Mode()&os.ModeNamedPipe == 0 {
+			return nil
+		}
+	}
+}
+
+// decodeStream logs the markers and payloads of r as they arrive.
+func decodeStream(r io.Reader, name string, log *eventLog, conf liveConfig) error {
+	var payloadSize int
+
+	dec := marker.NewDecoder(conf.Size, conf.Framing)
+
+	dec.OnMarker = func(m marker.Marker) {
+		log.Printf("%s: %s marker %q after %d bytes\n",
+			name, markerName(m.Size, "byte"), m.Data, m.Offset)
+	}
+
+	dec.OnPayload = func(p []byte, end bool) {
+		payloadSize += len(p)
+
+		if len(p) > 0 {
+			log.Printf("%s: payload %q\n", name, p)
+		}
+
+		if end {
+			log.Printf("%s: end of payload, %d bytes\n",
+				name, payloadSize)
+
+			payloadSize = 0
+		}
+	}
+
+	buf := make([]byte, 32*1024)
+
+	for {
+		n, err := r.Read(buf)
+
+		dec.Write(buf[:n])
+
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read stream: %w", err)
+		}
+	}
+
+	return dec.Close()
+}
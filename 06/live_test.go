@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hugowetterberg/advent2022/06/marker"
+)
+
+// lockedBuffer lets the test read the events while the server is still
+// writing them.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func lineConfig(t *testing.T) liveConfig {
+	t.Helper()
+
+	framing, err := marker.ParseFraming("line")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return liveConfig{Size: 4, Framing: framing}
+}
+
+func TestServeLoopback(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := &lockedBuffer{}
+	log := &eventLog{w: events}
+	conf := lineConfig(t)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- serve(l, log, conf)
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := conn.LocalAddr().String()
+
+	if _, err := conn.Write([]byte("aabcdhello\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Close()
+
+	want := name + ": end of payload"
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !strings.Contains(events.String(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the payload, got:\n%s", events)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	l.Close()
+
+	if err := <-done; err != nil {
+		t.Fatalf("serve returned %v", err)
+	}
+
+	checkEvents(t, events.String(), name)
+}
+
+func TestFollowRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "comm.log")
+
+	err := os.WriteFile(path, []byte("aabcdhello\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events bytes.Buffer
+
+	if err := followFIFO(path, &eventLog{w: &events}, lineConfig(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	checkEvents(t, events.String(), path)
+}
+
+// checkEvents checks that the events logged for the stream "aabcdhello\n"
+// report the marker and the payload, which may arrive in several parts.
+func checkEvents(t *testing.T, events, name string) {
+	t.Helper()
+
+	prefix := name + ": "
+
+	var payload strings.Builder
+
+	lines := strings.Split(strings.TrimSuffix(events, "\n"), "\n")
+
+	if len(lines) < 3 {
+		t.Fatalf("expected at least three events, got:\n%s", events)
+	}
+
+	wantMarker := prefix + `start-of-packet marker "abcd" after 5 bytes`
+	if lines[0] != wantMarker {
+		t.Errorf("first event is %q, want %q", lines[0], wantMarker)
+	}
+
+	for _, line := range lines[1 : len(lines)-1] {
+		if !strings.HasPrefix(line, prefix+"payload ") {
+			t.Fatalf("unexpected event %q", line)
+		}
+
+		part, err := strconv.Unquote(strings.TrimPrefix(line, prefix+"payload "))
+		if err != nil {
+			t.Fatalf("bad payload event %q: %v", line, err)
+		}
+
+		payload.WriteString(part)
+	}
+
+	if payload.String() != "hello" {
+		t.Errorf("payload is %q, want %q", payload.String(), "hello")
+	}
+
+	wantEnd := prefix + "end of payload, 5 bytes"
+	if last := lines[len(lines)-1]; last != wantEnd {
+		t.Errorf("last event is %q, want %q", last, wantEnd)
+	}
+}
@@ -1,9 +1,16 @@
 package main
 
 import (
-	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hugowetterberg/advent2022/06/marker"
 )
 
 func main() {
@@ -13,47 +20,290 @@ func main() {
 	}
 }
 
+// windowSizes is a repeatable flag for additional marker window sizes.
+type windowSizes []int
+
+func (w *windowSizes) String() string {
+	return fmt.Sprint(*w)
+}
+
+func (w *windowSizes) Set(v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid window size: %w", err)
+	}
+
+	if n < 1 {
+		return errors.New("window size must be at least 1")
+	}
+
+	*w = append(*w, n)
+
+	return nil
+}
+
+var names = map[int]string{
+	4:  "start-of-packet",
+	14: "start-of-message",
+}
+
+// markerName names the marker of the given size, unit being what the
+// window counts, bytes or runes.
+func markerName(size int, unit string) string {
+	if name, ok := names[size]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("%d-%s", size, unit)
+}
+
 func run() error {
-	seq := make([]byte, 14)
+	var (
+		extra               windowSizes
+		all, packets, runes bool
+		strip, liveSize     int
+		listen, fifo, frame string
+	)
 
-	r := bufio.NewReader(os.Stdin)
+	flag.Var(&extra, "window",
+		"additional marker window `size` to look for, can be repeated")
+	flag.BoolVar(&all, "all", false,
+		"list every marker position, not just the first")
+	flag.IntVar(&strip, "strip", 0,
+		"skip past the first marker of this `size` and copy the rest of the stream to stdout")
+	flag.BoolVar(&runes, "runes", false,
+		"decode the stream as UTF-8 and look for windows of distinct runes")
+	flag.BoolVar(&packets, "packets", false,
+		"split the stream into packets at every marker and write them as JSON lines, with base64 data")
+	flag.StringVar(&listen, "listen", "",
+		"decode live connections on a `url` like tcp://:9000 or unix:///tmp/comm.sock")
+	flag.StringVar(&fifo, "fifo", "",
+		"decode live data written to the named pipe at `path`")
+	flag.StringVar(&frame, "framing", "line",
+		"how live payloads end: stream, line or fixed:N")
+	flag.IntVar(&liveSize, "marker", 4,
+		"size of the marker that precedes live payloads and packets")
+	flag.Parse()
 
-	n, err := r.Read(seq)
+	mode, err := checkFlags()
 	if err != nil {
-		return fmt.Errorf("failed to read first %d bytes: %w",
-			len(seq), err)
+		return err
 	}
 
-	for {
-		if uniq(seq) {
+	switch mode {
+	case "strip":
+		if strip < 1 {
+			return errors.New("strip size must be at least 1")
+		}
+
+		return stripPreamble(os.Stdin, os.Stdout, strip)
+	case "packets":
+		if !packets {
 			break
 		}
 
-		copy(seq, seq[1:])
+		if liveSize < 1 {
+			return errors.New("marker size must be at least 1")
+		}
+
+		return decodePackets(os.Stdin, os.Stdout, os.Stderr, liveSize)
+	}
 
-		b, err := r.ReadByte()
+	if listen != "" || fifo != "" {
+		framing, err := marker.ParseFraming(frame)
 		if err != nil {
-			return fmt.Errorf("failed to read next byte: %w", err)
+			return err
+		}
+
+		if liveSize < 1 {
+			return errors.New("marker size must be at least 1")
 		}
 
-		n++
-		seq[len(seq)-1] = b
+		conf := liveConfig{Size: liveSize, Framing: framing}
+		log := &eventLog{w: os.Stdout}
+
+		if fifo != "" {
+			return followFIFO(fifo, log, conf)
+		}
+
+		network, address, err := parseListenAddr(listen)
+		if err != nil {
+			return err
+		}
+
+		l, err := net.Listen(network, address)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+
+		defer l.Close()
+
+		return serve(l, log, conf)
+	}
+
+	sizes := append([]int{4, 14}, extra...)
+
+	if runes {
+		return detectRunes(os.Stdin, os.Stdout, sizes, all)
 	}
 
-	println("sequence is:", string(seq),
-		"found after reading", n, "characters")
+	detector := marker.NewDetector(func(m marker.Marker) {
+		fmt.Fprintf(os.Stdout,
+			"%s marker %q found after reading %d characters\n",
+			markerName(m.Size, "byte"), m.Data, m.Offset)
+	}, sizes...)
+	detector.All = all
+
+	buf := make([]byte, 32*1024)
+
+	for !detector.Done() {
+		n, err := os.Stdin.Read(buf)
+
+		detector.Write(buf[:n])
+
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read after byte %d: %w",
+				detector.Offset(), err)
+		}
+	}
+
+	for i, size := range sizes {
+		if !detector.Found(i) {
+			fmt.Fprintf(os.Stdout, "no %s marker found\n",
+				markerName(size, "byte"))
+		}
+	}
 
 	return nil
 }
 
-func uniq(b []byte) bool {
-	for i := 0; i < len(b); i++ {
-		for j := i + 1; j < len(b); j++ {
-			if b[i] == b[j] {
-				return false
+// modes are the flags that select what to do with the stream, with
+// the other flags that apply to each of them. Without a mode flag the
+// stream is searched for markers.
+var modes = map[string][]string{
+	"strip":   nil,
+	"packets": {"marker"},
+	"listen":  {"framing", "marker"},
+	"fifo":    {"framing", "marker"},
+	"":        {"all", "runes", "window"},
+}
+
+// checkFlags returns the mode selected on the command line, or an error
+// if flags that don't apply together have been given.
+func checkFlags() (string, error) {
+	var set []string
+
+	flag.Visit(func(f *flag.Flag) {
+		set = append(set, f.Name)
+	})
+
+	var selected []string
+
+	for _, name := range set {
+		if _, ok := modes[name]; ok {
+			selected = append(selected, "-"+name)
+		}
+	}
+
+	if len(selected) > 1 {
+		return "", fmt.Errorf("%s can't be combined", listFlags(selected))
+	}
+
+	var mode string
+
+	if len(selected) == 1 {
+		mode = selected[0][1:]
+	}
+
+	for _, name := range set {
+		if name == mode || contains(modes[mode], name) {
+			continue
+		}
+
+		if mode != "" {
+			return "", fmt.Errorf("-%s can't be used with -%s", name, mode)
+		}
+
+		var users []string
+
+		for _, m := range []string{"packets", "listen", "fifo"} {
+			if contains(modes[m], name) {
+				users = append(users, "-"+m)
+			}
+		}
+
+		return "", fmt.Errorf("-%s only applies to %s", name, listFlags(users))
+	}
+
+	return mode, nil
+}
+
+func listFlags(names []string) string {
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func stripPreamble(in io.Reader, out io.Writer, size int) error {
+	r := marker.NewReader(in, size)
+
+	_, err := io.Copy(out, r)
+	if err != nil {
+		return fmt.Errorf("failed to copy stream: %w", err)
+	}
+
+	return nil
+}
+
+func detectRunes(in io.Reader, out io.Writer, sizes []int, all bool) error {
+	detector := marker.NewRuneDetector(func(m marker.RuneMarker) {
+		fmt.Fprintf(out,
+			"%s marker %q found after reading %d runes (%d bytes)\n",
+			markerName(m.Size, "rune"), m.Data, m.RuneOffset, m.ByteOffset)
+	}, sizes...)
+	detector.All = all
+
+	buf := make([]byte, 32*1024)
+
+	for !detector.Done() {
+		n, err := in.Read(buf)
+
+		if _, werr := detector.Write(buf[:n]); werr != nil {
+			return werr
+		}
+
+		if errors.Is(err, io.EOF) {
+			if err := detector.Close(); err != nil {
+				return err
 			}
+
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read after byte %d: %w",
+				detector.Offset(), err)
+		}
+	}
+
+	for i, size := range sizes {
+		if !detector.Found(i) {
+			fmt.Fprintf(out, "no %s marker found\n",
+				markerName(size, "rune"))
 		}
 	}
 
-	return true
+	return nil
 }
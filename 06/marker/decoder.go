@@ -0,0 +1,174 @@
+package marker
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FramingMode decides where the payload that follows a marker ends.
+type FramingMode int
+
+const (
+	// FrameStream payloads run until the end of the stream.
+	FrameStream FramingMode = iota
+	// FrameLine payloads end with a newline.
+	FrameLine
+	// FrameFixed payloads are a fixed number of bytes long.
+	FrameFixed
+)
+
+// Framing describes how payloads are delimited, Length is only used by
+// FrameFixed.
+type Framing struct {
+	Mode   FramingMode
+	Length int
+}
+
+// ParseFraming parses a framing description, one of "stream", "line" or
+// "fixed:N".
+func ParseFraming(s string) (Framing, error) {
+	mode, arg, hasArg := strings.Cut(s, ":")
+
+	switch {
+	case mode == "stream" && !hasArg:
+		return Framing{Mode: FrameStream}, nil
+	case mode == "line" && !hasArg:
+		return Framing{Mode: FrameLine}, nil
+	case mode == "fixed" && hasArg:
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 {
+			return Framing{}, fmt.Errorf(
+				"invalid fixed payload length %q", arg)
+		}
+
+		return Framing{Mode: FrameFixed, Length: n}, nil
+	}
+
+	return Framing{}, fmt.Errorf("unknown framing %q", s)
+}
+
+func (f Framing) String() string {
+	switch f.Mode {
+	case FrameStream:
+		return "stream"
+	case FrameLine:
+		return "line"
+	case FrameFixed:
+		return fmt.Sprintf("fixed:%d", f.Length)
+	}
+
+	return fmt.Sprintf("FramingMode(%d)", f.Mode)
+}
+
+// Decoder is an io.Writer that splits a stream into markers and the
+// payloads that follow them. The handlers are called as soon as the data
+// is written, so a payload can be reported in several parts, the last
+// one with end set. Once a line or fixed length payload has ended the
+// decoder looks for the next marker.
+type Decoder struct {
+	OnMarker  func(m Marker)
+	OnPayload func(p []byte, end bool)
+
+	size      int
+	framing   Framing
+	scanner   *Scanner
+	offset    int64
+	inPayload bool
+	remaining int
+}
+
+func NewDecoder(size int, framing Framing) *Decoder {
+	return &Decoder{
+		OnMarker:  func(Marker) {},
+		OnPayload: func([]byte, bool) {},
+		size:      size,
+		framing:   framing,
+		scanner:   NewScanner(size),
+	}
+}
+
+// Write decodes p, it never returns an error.
+func (d *Decoder) Write(p []byte) (int, error) {
+	n := len(p)
+
+	for len(p) > 0 {
+		if d.inPayload {
+			p = d.payload(p)
+			continue
+		}
+
+		for i, b := range p {
+			d.scanner.Push(b)
+			d.offset++
+
+			if !d.scanner.Unique(0) {
+				continue
+			}
+
+			d.OnMarker(Marker{
+				Size:   d.size,
+				Offset: d.offset,
+				Data:   d.scanner.Window(0),
+			})
+
+			d.inPayload = true
+			d.remaining = d.framing.Length
+			p = p[i+1:]
+
+			break
+		}
+
+		if !d.inPayload {
+			break
+		}
+	}
+
+	return n, nil
+}
+
+// payload consumes the payload part of p and returns the rest.
+func (d *Decoder) payload(p []byte) []byte {
+	var (
+		data = p
+		done bool
+		// delim is the number of delimiter bytes after the data.
+		delim int
+	)
+
+	switch d.framing.Mode {
+	case FrameLine:
+		if i := bytes.IndexByte(p, '\n'); i != -1 {
+			data, done, delim = p[:i], true, 1
+		}
+	case FrameFixed:
+		if d.remaining <= len(p) {
+			data, done = p[:d.remaining], true
+		}
+
+		d.remaining -= len(data)
+	}
+
+	d.OnPayload(data, done)
+
+	consumed := len(data) + delim
+	d.offset += int64(consumed)
+
+	if done {
+		d.inPayload = false
+		d.scanner.Reset()
+	}
+
+	return p[consumed:]
+}
+
+// Close ends a payload that is in progress, as the stream has ended.
+func (d *Decoder) Close() error {
+	if d.inPayload {
+		d.OnPayload(nil, true)
+		d.inPayload = false
+	}
+
+	return nil
+}
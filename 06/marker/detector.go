@@ -0,0 +1,153 @@
+package marker
+
+import (
+	"errors"
+	"io"
+)
+
+// Marker is a marker that was found in a stream.
+type Marker struct {
+	// Size is the size of the window the marker was found with.
+	Size int
+	// Offset is the number of bytes that had been read when the
+	// marker was found, which also is the offset of the first byte
+	// after the marker.
+	Offset int64
+	// Data is the marker itself.
+	Data []byte
+}
+
+// Detector is an io.Writer that looks for markers in the data written to
+// it and reports them to a callback. By default only the first marker of
+// each size is reported, set All to report every position where the
+// stream ends with a marker.
+//
+// The callback is called synchronously from Write, a callback that sends
+// the marker on a channel can be used to consume markers from another
+// goroutine.
+type Detector struct {
+	All bool
+
+	scanner   *Scanner
+	found     []bool
+	remaining int
+	fn        func(m Marker)
+}
+
+// NewDetector creates a detector for markers of the given sizes.
+func NewDetector(fn func(m Marker), sizes ...int) *Detector {
+	return &Detector{
+		scanner:   NewScanner(sizes...),
+		found:     make([]bool, len(sizes)),
+		remaining: len(sizes),
+		fn:        fn,
+	}
+}
+
+// Write scans p for markers, it never returns an error.
+func (d *Detector) Write(p []byte) (int, error) {
+	for _, b := range p {
+		d.scanner.Push(b)
+
+		for i := range d.found {
+			if (d.found[i] && !d.All) || !d.scanner.Unique(i) {
+				continue
+			}
+
+			if !d.found[i] {
+				d.found[i] = true
+				d.remaining--
+			}
+
+			d.fn(Marker{
+				Size:   d.scanner.Size(i),
+				Offset: d.scanner.Offset(),
+				Data:   d.scanner.Window(i),
+			})
+		}
+	}
+
+	return len(p), nil
+}
+
+// Done reports whether there is nothing left to look for, that is when
+// a marker of every size has been found and All isn't set.
+func (d *Detector) Done() bool {
+	return d.remaining == 0 && !d.All
+}
+
+// Found reports whether a marker of window i has been found.
+func (d *Detector) Found(i int) bool {
+	return d.found[i]
+}
+
+// Offset returns the number of bytes that have been written.
+func (d *Detector) Offset() int64 {
+	return d.scanner.Offset()
+}
+
+// ErrNoMarker is returned by Reader when the underlying reader runs out
+// of data before a marker has been found.
+var ErrNoMarker = errors.New("marker: no marker found")
+
+// Reader skips the preamble and the first marker of the given size in
+// the underlying reader, and then yields the rest of the stream.
+type Reader struct {
+	r       io.Reader
+	scanner *Scanner
+	marker  *Marker
+}
+
+func NewReader(r io.Reader, size int) *Reader {
+	return &Reader{
+		r:       r,
+		scanner: NewScanner(size),
+	}
+}
+
+// Marker returns the marker that ended the preamble, or nil if it
+// hasn't been found yet.
+func (r *Reader) Marker() *Marker {
+	return r.marker
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.marker != nil {
+		return r.r.Read(p)
+	}
+
+	for len(p) > 0 {
+		n, err := r.r.Read(p)
+
+		for i, b := range p[:n] {
+			r.scanner.Push(b)
+
+			if !r.scanner.Unique(0) {
+				continue
+			}
+
+			r.marker = &Marker{
+				Size:   r.scanner.Size(0),
+				Offset: r.scanner.Offset(),
+				Data:   r.scanner.Window(0),
+			}
+
+			rest := copy(p, p[i+1:n])
+			if rest == 0 && err == nil {
+				return r.r.Read(p)
+			}
+
+			return rest, err
+		}
+
+		if errors.Is(err, io.EOF) {
+			return 0, ErrNoMarker
+		}
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,82 @@
+package marker
+
+// Packet is the data between a marker and the next one, or the end of
+// the stream.
+type Packet struct {
+	// Marker is the marker that precedes the packet.
+	Marker []byte
+	// Offset is the offset of the first byte of the packet.
+	Offset int64
+	Data   []byte
+}
+
+// Splitter is an io.Writer that splits a stream into packets at every
+// marker of a given size. Everything before the first marker is
+// discarded. Markers don't overlap, the search for the next marker
+// starts right after the previous one, so a marker that directly
+// follows another one yields an empty packet.
+type Splitter struct {
+	OnPacket func(p Packet)
+
+	scanner *Scanner
+	size    int
+	offset  int64
+	marker  []byte
+	start   int64
+	buf     []byte
+}
+
+func NewSplitter(size int, fn func(p Packet)) *Splitter {
+	return &Splitter{
+		OnPacket: fn,
+		scanner:  NewScanner(size),
+		size:     size,
+	}
+}
+
+// Write splits p into packets, it never returns an error.
+func (s *Splitter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		s.scanner.Push(b)
+		s.offset++
+
+		if s.marker != nil {
+			s.buf = append(s.buf, b)
+		}
+
+		if !s.scanner.Unique(0) {
+			continue
+		}
+
+		if s.marker != nil {
+			// The marker bytes were collected as part of the
+			// packet before we knew that they were a marker.
+			s.emit(s.buf[:len(s.buf)-s.size])
+		}
+
+		s.marker = s.scanner.Window(0)
+		s.start = s.offset
+		s.buf = nil
+		s.scanner.Reset()
+	}
+
+	return len(p), nil
+}
+
+// Close emits the last packet, which ends with the stream.
+func (s *Splitter) Close() error {
+	if s.marker != nil {
+		s.emit(s.buf)
+		s.marker = nil
+	}
+
+	return nil
+}
+
+func (s *Splitter) emit(data []byte) {
+	s.OnPacket(Packet{
+		Marker: s.marker,
+		Offset: s.start,
+		Data:   data,
+	})
+}
@@ -0,0 +1,272 @@
+package marker
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// RuneScanner is the rune counterpart of Scanner, it tracks windows of
+// runes rather than bytes. The counts are kept in maps, so adding a rune
+// still costs O(1) per window.
+type RuneScanner struct {
+	ring    []rune
+	mask    int64
+	n       int64
+	windows []runeWindow
+}
+
+type runeWindow struct {
+	size   int64
+	counts map[rune]int32
+	dups   int
+}
+
+// NewRuneScanner creates a scanner for windows of the given sizes.
+// Sizes must be at least 1.
+func NewRuneScanner(sizes ...int) *RuneScanner {
+	var longest int
+
+	windows := make([]runeWindow, len(sizes))
+
+	for i, size := range sizes {
+		if size < 1 {
+			panic("marker: window size must be at least 1")
+		}
+
+		windows[i].size = int64(size)
+		windows[i].counts = make(map[rune]int32, size)
+
+		if size > longest {
+			longest = size
+		}
+	}
+
+	ringSize := 1
+	for ringSize < longest {
+		ringSize <<= 1
+	}
+
+	return &RuneScanner{
+		ring:    make([]rune, ringSize),
+		mask:    int64(ringSize - 1),
+		windows: windows,
+	}
+}
+
+// Push adds the next rune of the stream to all windows.
+func (s *RuneScanner) Push(r rune) {
+	n, ring, mask := s.n, s.ring, s.mask
+
+	for i := range s.windows {
+		w := &s.windows[i]
+
+		w.counts[r]++
+		if w.counts[r] == 2 {
+			w.dups++
+		}
+
+		if n >= w.size {
+			out := ring[(n-w.size)&mask]
+
+			w.counts[out]--
+
+			// Drop runes that have left the window, so that
+			// the maps don't grow with the alphabet.
+			switch w.counts[out] {
+			case 0:
+				delete(w.counts, out)
+			case 1:
+				w.dups--
+			}
+		}
+	}
+
+	ring[n&mask] = r
+	s.n = n + 1
+}
+
+// Unique reports whether window i is full and holds no repeated runes.
+func (s *RuneScanner) Unique(i int) bool {
+	w := &s.windows[i]
+
+	return w.dups == 0 && s.n >= w.size
+}
+
+// Size returns the size of window i.
+func (s *RuneScanner) Size(i int) int {
+	return int(s.windows[i].size)
+}
+
+// Offset returns the number of runes that have been pushed.
+func (s *RuneScanner) Offset() int64 {
+	return s.n
+}
+
+// Window returns the current contents of window i.
+func (s *RuneScanner) Window(i int) string {
+	size := s.windows[i].size
+	if s.n < size {
+		size = s.n
+	}
+
+	r := make([]rune, size)
+
+	for j := range r {
+		r[j] = s.ring[(s.n-size+int64(j))&s.mask]
+	}
+
+	return string(r)
+}
+
+// RuneMarker is a marker that was found in a stream of runes.
+type RuneMarker struct {
+	Size int
+	// RuneOffset and ByteOffset are the number of runes and bytes
+	// that had been read when the marker was found.
+	RuneOffset int64
+	ByteOffset int64
+	Data       string
+}
+
+// EncodingError is returned when a stream isn't valid UTF-8.
+type EncodingError struct {
+	// ByteOffset is the offset of the first byte of the invalid
+	// sequence, and RuneOffset the number of valid runes before it.
+	ByteOffset int64
+	RuneOffset int64
+	Truncated  bool
+}
+
+func (e *EncodingError) Error() string {
+	what := "invalid UTF-8"
+	if e.Truncated {
+		what = "truncated UTF-8 sequence"
+	}
+
+	return fmt.Sprintf("%s at byte offset %d, rune offset %d",
+		what, e.ByteOffset, e.RuneOffset)
+}
+
+// RuneDetector is the rune counterpart of Detector. It decodes the UTF-8
+// written to it and looks for windows of distinct runes. Runes can be
+// split across writes.
+type RuneDetector struct {
+	All bool
+
+	scanner   *RuneScanner
+	found     []bool
+	remaining int
+	fn        func(m RuneMarker)
+	bytes     int64
+	pending   []byte
+	err       error
+}
+
+func NewRuneDetector(fn func(m RuneMarker), sizes ...int) *RuneDetector {
+	return &RuneDetector{
+		scanner:   NewRuneScanner(sizes...),
+		found:     make([]bool, len(sizes)),
+		remaining: len(sizes),
+		fn:        fn,
+	}
+}
+
+// Write decodes p and scans it for markers. An *EncodingError is
+// returned if p contains invalid UTF-8, and the detector will refuse any
+// further writes.
+func (d *RuneDetector) Write(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	buf := p
+	carried := len(d.pending)
+
+	if carried > 0 {
+		buf = append(d.pending, p...)
+		d.pending = nil
+	}
+
+	for len(buf) > 0 {
+		if !utf8.FullRune(buf) {
+			d.pending = append(d.pending, buf...)
+			break
+		}
+
+		r, size := utf8.DecodeRune(buf)
+		if r == utf8.RuneError && size == 1 {
+			d.err = &EncodingError{
+				ByteOffset: d.bytes,
+				RuneOffset: d.scanner.Offset(),
+			}
+
+			// The invalid byte can be in the part that was
+			// carried over from the previous write.
+			written := len(p) - len(buf)
+			if written < 0 {
+				written = 0
+			}
+
+			return written, d.err
+		}
+
+		d.bytes += int64(size)
+		d.push(r)
+
+		buf = buf[size:]
+	}
+
+	return len(p), nil
+}
+
+func (d *RuneDetector) push(r rune) {
+	d.scanner.Push(r)
+
+	for i := range d.found {
+		if (d.found[i] && !d.All) || !d.scanner.Unique(i) {
+			continue
+		}
+
+		if !d.found[i] {
+			d.found[i] = true
+			d.remaining--
+		}
+
+		d.fn(RuneMarker{
+			Size:       d.scanner.Size(i),
+			RuneOffset: d.scanner.Offset(),
+			ByteOffset: d.bytes,
+			Data:       d.scanner.Window(i),
+		})
+	}
+}
+
+// Close returns an *EncodingError if the stream ended in the middle of
+// a rune.
+func (d *RuneDetector) Close() error {
+	if d.err == nil && len(d.pending) > 0 {
+		d.err = &EncodingError{
+			ByteOffset: d.bytes,
+			RuneOffset: d.scanner.Offset(),
+			Truncated:  true,
+		}
+	}
+
+	return d.err
+}
+
+// Done reports whether a marker of every size has been found and All
+// isn't set.
+func (d *RuneDetector) Done() bool {
+	return d.remaining == 0 && !d.All
+}
+
+// Found reports whether a marker of window i has been found.
+func (d *RuneDetector) Found(i int) bool {
+	return d.found[i]
+}
+
+// Offset returns the number of bytes that have been decoded.
+func (d *RuneDetector) Offset() int64 {
+	return d.bytes
+}
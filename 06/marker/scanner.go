@@ -0,0 +1,129 @@
+// Package marker finds markers in datastreams. A marker is a window of
+// a given size where no symbol occurs more than once.
+package marker
+
+// Scanner tracks a number of marker windows of different sizes over a
+// stream of bytes. Each window keeps a count of every byte value in it
+// and the number of values that occur more than once, so adding a byte
+// to the stream costs O(1) per window, regardless of its size.
+type Scanner struct {
+	ring    []byte
+	mask    int64
+	n       int64
+	windows []window
+}
+
+type window struct {
+	size   int64
+	counts [256]int32
+	dups   int
+}
+
+// NewScanner creates a scanner for windows of the given sizes. Sizes
+// must be at least 1.
+func NewScanner(sizes ...int) *Scanner {
+	var longest int
+
+	windows := make([]window, len(sizes))
+
+	for i, size := range sizes {
+		if size < 1 {
+			panic("marker: window size must be at least 1")
+		}
+
+		windows[i].size = int64(size)
+
+		if size > longest {
+			longest = size
+		}
+	}
+
+	// Round the ring up to a power of two so that positions can be
+	// masked rather than divided.
+	ringSize := 1
+	for ringSize < longest {
+		ringSize <<= 1
+	}
+
+	return &Scanner{
+		ring:    make([]byte, ringSize),
+		mask:    int64(ringSize - 1),
+		windows: windows,
+	}
+}
+
+// Push adds the next byte of the stream to all windows.
+func (s *Scanner) Push(b byte) {
+	n, ring, mask := s.n, s.ring, s.mask
+
+	for i := range s.windows {
+		w := &s.windows[i]
+		dups := w.dups
+
+		w.counts[b]++
+		if w.counts[b] == 2 {
+			dups++
+		}
+
+		// Drop the byte that fell out of the window. The ring
+		// hasn't been written to yet, so the oldest byte is still
+		// there even when the window is as long as the ring.
+		if n >= w.size {
+			out := ring[(n-w.size)&mask]
+
+			w.counts[out]--
+			if w.counts[out] == 1 {
+				dups--
+			}
+		}
+
+		w.dups = dups
+	}
+
+	ring[n&mask] = b
+	s.n = n + 1
+}
+
+// Reset clears all windows, as if nothing had been pushed.
+func (s *Scanner) Reset() {
+	for i := range s.windows {
+		s.windows[i].counts = [256]int32{}
+		s.windows[i].dups = 0
+	}
+
+	s.n = 0
+}
+
+// Unique reports whether window i is full and holds no repeated bytes,
+// in which case the stream currently ends with a marker.
+func (s *Scanner) Unique(i int) bool {
+	w := &s.windows[i]
+
+	return w.dups == 0 && s.n >= w.size
+}
+
+// Size returns the size of window i.
+func (s *Scanner) Size(i int) int {
+	return int(s.windows[i].size)
+}
+
+// Offset returns the number of bytes that have been pushed.
+func (s *Scanner) Offset() int64 {
+	return s.n
+}
+
+// Window returns a copy of the current contents of window i.
+func (s *Scanner) Window(i int) []byte {
+	size := s.windows[i].size
+	if s.n < size {
+		size = s.n
+	}
+
+	b := make([]byte, size)
+
+	for j := range b {
+		b[j] = s.ring[(s.n-size+int64(j))&s.mask]
+	}
+
+	return b
+}
@@ -0,0 +1,149 @@
+package marker
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func randomStream(n, alphabet int, seed int64) []byte {
+	rnd := rand.New(rand.NewSource(seed))
+
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(rnd.Intn(alphabet))
+	}
+
+	return data
+}
+
+// uniq is the quadratic check that the scanner replaced.
+func uniq(b []byte) bool {
+	for i := 0; i < len(b); i++ {
+		for j := i + 1; j < len(b); j++ {
+			if b[i] == b[j] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func TestScannerMatchesUniq(t *testing.T) {
+	sizes := []int{1, 2, 3, 4, 8, 14, 16}
+
+	// Small alphabets make the incoming byte often equal to the one
+	// leaving the window, large ones let the big windows be unique.
+	for _, alphabet := range []int{2, 4, 16, 64, 256} {
+		data := randomStream(5000, alphabet, int64(alphabet))
+
+		scanners := make([]*Scanner, len(sizes))
+		for i, size := range sizes {
+			scanners[i] = NewScanner(size)
+		}
+
+		// A scanner with all the sizes shares one ring between them.
+		all := NewScanner(sizes...)
+
+		for n, b := range data {
+			all.Push(b)
+
+			for i, size := range sizes {
+				s := scanners[i]
+				s.Push(b)
+
+				start := n + 1 - size
+				if start < 0 {
+					start = 0
+				}
+
+				window := data[start : n+1]
+				want := len(window) == size && uniq(window)
+
+				for _, sc := range []struct {
+					name string
+					s    *Scanner
+					i    int
+				}{{"single", s, 0}, {"shared", all, i}} {
+					if got := sc.s.Unique(sc.i); got != want {
+						t.Fatalf("alphabet %d, %s window %d after %d bytes: Unique() = %v, want %v",
+							alphabet, sc.name, size, n+1, got, want)
+					}
+
+					if got := sc.s.Window(sc.i); !bytes.Equal(got, window) {
+						t.Fatalf("alphabet %d, %s window %d after %d bytes: Window() = %v, want %v",
+							alphabet, sc.name, size, n+1, got, window)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestScannerReset(t *testing.T) {
+	s := NewScanner(4)
+
+	for _, b := range []byte("abcabc") {
+		s.Push(b)
+	}
+
+	s.Reset()
+
+	for _, b := range []byte("abc") {
+		s.Push(b)
+
+		if s.Unique(0) {
+			t.Fatalf("window is unique after %d bytes", s.Offset())
+		}
+	}
+
+	s.Push('d')
+
+	if !s.Unique(0) || string(s.Window(0)) != "abcd" {
+		t.Fatalf("Unique() = %v, Window() = %q after reset, want true, abcd",
+			s.Unique(0), s.Window(0))
+	}
+}
+
+func BenchmarkScanner(b *testing.B) {
+	data := randomStream(1<<20, 26, 1)
+
+	for _, windows := range [][]int{{4}, {14}, {64}, {256}, {4, 14, 64, 256}} {
+		b.Run(fmt.Sprint(windows), func(b *testing.B) {
+			s := NewScanner(windows...)
+
+			var markers int
+
+			b.SetBytes(int64(len(data)))
+
+			for i := 0; i < b.N; i++ {
+				for _, c := range data {
+					s.Push(c)
+
+					for w := range windows {
+						if s.Unique(w) {
+							markers++
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDetector(b *testing.B) {
+	data := randomStream(1<<20, 26, 1)
+
+	b.SetBytes(int64(len(data)))
+
+	for i := 0; i < b.N; i++ {
+		d := NewDetector(func(Marker) {}, 4, 14)
+		d.All = true
+
+		if _, err := d.Write(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
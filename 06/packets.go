@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/hugowetterberg/advent2022/06/marker"
+)
+
+// packetLine is the JSON form of a packet. The marker and payload are
+// raw bytes from the stream, so they are base64 encoded, as strings
+// can't hold invalid UTF-8.
+type packetLine struct {
+	Offset  int64  `json:"offset"`
+	Length  int    `json:"length"`
+	Marker  []byte `json:"marker"`
+	Payload []byte `json:"payload"`
+}
+
+// decodePackets writes every packet in r as a JSON line to out, followed
+// by statistics on the packet sizes to stats.
+func decodePackets(r io.Reader, out, stats io.Writer, size int) error {
+	var (
+		sizes  []int
+		encErr error
+	)
+
+	enc := json.NewEncoder(out)
+
+	splitter := marker.NewSplitter(size, func(p marker.Packet) {
+		sizes = append(sizes, len(p.Data))
+
+		if encErr != nil {
+			return
+		}
+
+		encErr = enc.Encode(packetLine{
+			Offset:  p.Offset,
+			Length:  len(p.Data),
+			Marker:  p.Marker,
+			Payload: p.Data,
+		})
+	})
+
+	buf := make([]byte, 32*1024)
+
+	for {
+		n, err := r.Read(buf)
+
+		splitter.Write(buf[:n])
+
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read stream: %w", err)
+		}
+	}
+
+	splitter.Close()
+
+	if encErr != nil {
+		return fmt.Errorf("failed to write packet: %w", encErr)
+	}
+
+	printPacketStats(stats, sizes)
+
+	return nil
+}
+
+func printPacketStats(w io.Writer, sizes []int) {
+	if len(sizes) == 0 {
+		fmt.Fprintln(w, "no packets found")
+		return
+	}
+
+	sort.Ints(sizes)
+
+	var total, empty int
+
+	for _, s := range sizes {
+		total += s
+
+		if s == 0 {
+			empty++
+		}
+	}
+
+	median := float64(sizes[len(sizes)/2])
+	if len(sizes)%2 == 0 {
+		median = float64(sizes[len(sizes)/2-1]+sizes[len(sizes)/2]) / 2
+	}
+
+	fmt.Fprintf(w, "packets: %d (%d empty)\n", len(sizes), empty)
+	fmt.Fprintf(w, "total:   %d bytes\n", total)
+	fmt.Fprintf(w, "min:     %d bytes\n", sizes[0])
+	fmt.Fprintf(w, "max:     %d bytes\n", sizes[len(sizes)-1])
+	fmt.Fprintf(w, "mean:    %.2f bytes\n", float64(total)/float64(len(sizes)))
+	fmt.Fprintf(w, "median:  %.1f bytes\n", median)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// change is a difference between two filesystem snapshots.
+type change struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path"`
+	IsDir   bool   `json:"dir"`
+	OldSize int    `json:"old_size"`
+	NewSize int    `json:"new_size"`
+	Delta   int    `json:"delta"`
+}
+
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeResized = "resized"
+)
+
+type diffSummary struct {
+	OldTotal int      `json:"old_total"`
+	NewTotal int      `json:"new_total"`
+	Delta    int      `json:"delta"`
+	Changes  []change `json:"changes"`
+}
+
+// diffTrees compares two trees. Added and removed directories are
+// reported as a whole, without their contents. Directories that exist
+// in both trees are reported as resized when their total size differs,
+// which gives the size delta for every directory along the way to a
+// change.
+func diffTrees(oldRoot, newRoot *Node) []change {
+	var changes []change
+
+	var visit func(old, cur *Node)
+
+	visit = func(old, cur *Node) {
+		if old.TotalSize() != cur.TotalSize() {
+			changes = append(changes, change{
+				Kind:    changeResized,
+				Path:    nodePath(cur),
+				IsDir:   cur.IsDir,
+				OldSize: old.TotalSize(),
+				NewSize: cur.TotalSize(),
+				Delta:   cur.TotalSize() - old.TotalSize(),
+			})
+		}
+
+		names := make(map[string]bool)
+
+		for name := range old.Children {
+			names[name] = true
+		}
+
+		for name := range cur.Children {
+			names[name] = true
+		}
+
+		sorted := make([]string, 0, len(names))
+		for name := range names {
+			sorted = append(sorted, name)
+		}
+
+		sort.Strings(sorted)
+
+		for _, name := range sorted {
+			o, n := old.Children[name], cur.Children[name]
+
+			// A file that became a directory, or the other way
+			// around, is both removed and added.
+			if o != nil && n != nil && o.IsDir != n.IsDir {
+				changes = append(changes, removedChange(o), addedChange(n))
+				continue
+			}
+
+			switch {
+			case o == nil:
+				changes = append(changes, addedChange(n))
+			case n == nil:
+				changes = append(changes, removedChange(o))
+			default:
+				visit(o, n)
+			}
+		}
+	}
+
+	visit(oldRoot, newRoot)
+
+	return changes
+}
+
+func addedChange(n *Node) change {
+	return change{
+		Kind:    changeAdded,
+		Path:    nodePath(n),
+		IsDir:   n.IsDir,
+		NewSize: n.TotalSize(),
+		Delta:   n.TotalSize(),
+	}
+}
+
+func removedChange(n *Node) change {
+	return change{
+		Kind:    changeRemoved,
+		Path:    nodePath(n),
+		IsDir:   n.IsDir,
+		OldSize: n.TotalSize(),
+		Delta:   -n.TotalSize(),
+	}
+}
+
+func printDiff(w io.Writer, summary diffSummary) {
+	marks := map[string]string{
+		changeAdded:   "+",
+		changeRemoved: "-",
+		changeResized: "~",
+	}
+
+	for _, c := range summary.Changes {
+		path := c.Path
+		if c.IsDir && path != "/" {
+			path += "/"
+		}
+
+		switch c.Kind {
+		case changeAdded:
+			fmt.Fprintf(w, "%s %s %d\n", marks[c.Kind], path, c.NewSize)
+		case changeRemoved:
+			fmt.Fprintf(w, "%s %s %d\n", marks[c.Kind], path, c.OldSize)
+		default:
+			fmt.Fprintf(w, "%s %s %d -> %d (%+d)\n",
+				marks[c.Kind], path, c.OldSize, c.NewSize, c.Delta)
+		}
+	}
+
+	fmt.Fprintf(w, "total %d -> %d (%+d)\n",
+		summary.OldTotal, summary.NewTotal, summary.Delta)
+}
+
+func diffCommand(_ *Node, args []string) error {
+	var asJSON bool
+
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.BoolVar(&asJSON, "json", false, "write the differences as JSON")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 2 {
+		return errors.New("usage: diff [-json] OLD NEW")
+	}
+
+	oldRoot, err := loadTranscript(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to load %q: %w", flags.Arg(0), err)
+	}
+
+	newRoot, err := loadTranscript(flags.Arg(1))
+	if err != nil {
+		return fmt.Errorf("failed to load %q: %w", flags.Arg(1), err)
+	}
+
+	summary := diffSummary{
+		OldTotal: oldRoot.TotalSize(),
+		NewTotal: newRoot.TotalSize(),
+		Delta:    newRoot.TotalSize() - oldRoot.TotalSize(),
+		Changes:  diffTrees(oldRoot, newRoot),
+	}
+
+	if !asJSON {
+		printDiff(os.Stdout, summary)
+		return nil
+	}
+
+	if summary.Changes == nil {
+		summary.Changes = []change{}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(summary)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// humanSize formats a size with binary units, like du -h.
+func humanSize(size int) string {
+	const units = "KMGTPE"
+
+	if size < 1024 {
+		return strconv.Itoa(size)
+	}
+
+	s := float64(size)
+	i := -1
+
+	for s >= 1024 && i < len(units)-1 {
+		s /= 1024
+		i++
+	}
+
+	return fmt.Sprintf("%.1f%c", s, units[i])
+}
+
+func formatSize(size int, human bool) string {
+	if human {
+		return humanSize(size)
+	}
+
+	return strconv.Itoa(size)
+}
+
+func percentOf(size, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return 100 * float64(size) / float64(total)
+}
+
+// sortedChildren returns the children of n, largest first when bySize
+// is set, and by name otherwise.
+func sortedChildren(n *Node, bySize bool) []*Node {
+	children := make([]*Node, 0, len(n.Children))
+
+	for _, c := range n.Children {
+		children = append(children, c)
+	}
+
+	sort.Slice(children, func(i, j int) bool {
+		a, b := children[i], children[j]
+
+		if bySize && a.TotalSize() != b.TotalSize() {
+			return a.TotalSize() > b.TotalSize()
+		}
+
+		return a.Name < b.Name
+	})
+
+	return children
+}
+
+func nodePath(n *Node) string {
+	if n.Parent == nil {
+		return "/"
+	}
+
+	var elems []string
+
+	for ; n.Parent != nil; n = n.Parent {
+		elems = append(elems, n.Name)
+	}
+
+	for i, j := 0, len(elems)-1; i < j; i, j = i+1, j-1 {
+		elems[i], elems[j] = elems[j], elems[i]
+	}
+
+	return "/" + strings.Join(elems, "/")
+}
+
+type duOptions struct {
+	Human    bool
+	All      bool
+	BySize   bool
+	MaxDepth int
+}
+
+func duCommand(root *Node, args []string) error {
+	var (
+		opts   duOptions
+		sortBy string
+	)
+
+	flags := flag.NewFlagSet("du", flag.ContinueOnError)
+	flags.BoolVar(&opts.Human, "h", false, "print sizes in human readable units")
+	flags.BoolVar(&opts.All, "a", false, "list files as well as directories")
+	flags.StringVar(&sortBy, "sort", "size", "sort entries by `size` or name")
+	flags.IntVar(&opts.MaxDepth, "depth", -1, "only list entries down to this depth, -1 for no limit")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	switch sortBy {
+	case "size":
+		opts.BySize = true
+	case "name":
+	default:
+		return fmt.Errorf("invalid sort order %q", sortBy)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+
+	printDu(w, root, opts)
+
+	return w.Flush()
+}
+
+// printDu writes a du style report with the size of every directory
+// and its share of its parent directory.
+func printDu(w io.Writer, root *Node, opts duOptions) {
+	var visit func(n *Node, depth int, indent string)
+
+	visit = func(n *Node, depth int, indent string) {
+		parentTotal := n.TotalSize()
+		if n.Parent != nil {
+			parentTotal = n.Parent.TotalSize()
+		}
+
+		name := nodePath(n)
+		if n.Parent != nil {
+			name = n.Name
+		}
+
+		if n.IsDir && n.Parent != nil {
+			name += "/"
+		}
+
+		fmt.Fprintf(w, "%10s %6.1f%%  %s%s\n",
+			formatSize(n.TotalSize(), opts.Human),
+			percentOf(n.TotalSize(), parentTotal), indent, name)
+
+		if opts.MaxDepth >= 0 && depth >= opts.MaxDepth {
+			return
+		}
+
+		for _, c := range sortedChildren(n, opts.BySize) {
+			if c.IsDir || opts.All {
+				visit(c, depth+1, indent+"  ")
+			}
+		}
+	}
+
+	visit(root, 0, "")
+}
+
+func ncduCommand(root *Node, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: ncdu")
+	}
+
+	return browse(os.Stdin, os.Stdout, root)
+}
+
+const barWidth = 20
+
+// browse is a simple ncdu style browser, it lists the entries of a
+// directory by size and lets the user drill down into them.
+func browse(in io.Reader, out io.Writer, root *Node) error {
+	cwd := root
+	s := bufio.NewScanner(in)
+
+	for {
+		children := sortedChildren(cwd, true)
+
+		fmt.Fprint(out, "\x1b[H\x1b[2J")
+		fmt.Fprintf(out, "--- %s  total %s ---\n\n",
+			nodePath(cwd), humanSize(cwd.TotalSize()))
+
+		for i, c := range children {
+			share := percentOf(c.TotalSize(), cwd.TotalSize())
+			filled := int(share / 100 * barWidth)
+
+			name := c.Name
+			if c.IsDir {
+				name += "/"
+			}
+
+			fmt.Fprintf(out, "%4d %10s %5.1f%% [%s%s] %s\n",
+				i+1, humanSize(c.TotalSize()), share,
+				strings.Repeat("#", filled),
+				strings.Repeat(" ", barWidth-filled), name)
+		}
+
+		fmt.Fprint(out, "\nnumber to open, .. to go up, q to quit: ")
+
+		if !s.Scan() {
+			fmt.Fprintln(out)
+			return s.Err()
+		}
+
+		input := strings.TrimSpace(s.Text())
+
+		switch input {
+		case "q":
+			return nil
+		case "..":
+			if cwd.Parent != nil {
+				cwd = cwd.Parent
+			}
+
+			continue
+		}
+
+		i, err := strconv.Atoi(input)
+		if err != nil || i < 1 || i > len(children) || !children[i-1].IsDir {
+			continue
+		}
+
+		cwd = children[i-1]
+	}
+}
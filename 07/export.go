@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	typeDir  = "dir"
+	typeFile = "file"
+)
+
+// jsonNode is the JSON representation of a Node. TotalSize is written
+// on export, but ignored on import as it's derived from the sizes.
+type jsonNode struct {
+	Name      string      `json:"name"`
+	Type      string      `json:"type"`
+	Size      int         `json:"size"`
+	TotalSize int         `json:"total_size"`
+	Children  []*jsonNode `json:"children,omitempty"`
+}
+
+func toJSONNode(n *Node) *jsonNode {
+	j := jsonNode{
+		Name:      n.Name,
+		Type:      typeFile,
+		Size:      n.Size,
+		TotalSize: n.TotalSize(),
+	}
+
+	if n.Parent == nil {
+		j.Name = "/"
+	}
+
+	if n.IsDir {
+		j.Type = typeDir
+		j.Children = []*jsonNode{}
+
+		for _, c := range sortedChildren(n, false) {
+			j.Children = append(j.Children, toJSONNode(c))
+		}
+	}
+
+	return &j
+}
+
+func writeJSON(w io.Writer, root *Node) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(toJSONNode(root))
+}
+
+// writeTree writes the tree in the format used by the puzzle description,
+// one "- name (dir)" or "- name (file, size=N)" line per node, indented
+// two spaces per level.
+func writeTree(w io.Writer, root *Node) {
+	var visit func(n *Node, indent string)
+
+	visit = func(n *Node, indent string) {
+		name := n.Name
+		if n.Parent == nil {
+			name = "/"
+		}
+
+		if n.IsDir {
+			fmt.Fprintf(w, "%s- %s (dir)\n", indent, name)
+		} else {
+			fmt.Fprintf(w, "%s- %s (file, size=%d)\n", indent, name, n.Size)
+		}
+
+		for _, c := range sortedChildren(n, false) {
+			visit(c, indent+"  ")
+		}
+	}
+
+	visit(root, "")
+}
+
+// readJSON builds a Node tree from its JSON representation.
+func readJSON(r io.Reader) (*Node, error) {
+	var j jsonNode
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&j); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if j.Type != typeDir {
+		return nil, errors.New("the root must be a directory")
+	}
+
+	root := NewNode(nil)
+	root.IsDir = true
+
+	if err := importChildren(root, &j, "/"); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+func importChildren(parent *Node, j *jsonNode, path string) error {
+	for _, c := range j.Children {
+		if c == nil {
+			return fmt.Errorf("%s: null child", path)
+		}
+
+		if c.Name == "" || c.Name == "." || c.Name == ".." || strings.Contains(c.Name, "/") {
+			return fmt.Errorf("%s: invalid name %q", path, c.Name)
+		}
+
+		childPath := strings.TrimSuffix(path, "/") + "/" + c.Name
+
+		if _, exists := parent.Children[c.Name]; exists {
+			return fmt.Errorf("%s: listed more than once", childPath)
+		}
+
+		child := NewNode(parent)
+		child.Name = c.Name
+
+		switch c.Type {
+		case typeDir:
+			child.IsDir = true
+
+			if c.Size != 0 {
+				return fmt.Errorf("%s: directories can't have a size of their own", childPath)
+			}
+		case typeFile:
+			if c.Size < 0 {
+				return fmt.Errorf("%s: negative size", childPath)
+			}
+
+			if len(c.Children) > 0 {
+				return fmt.Errorf("%s: files can't have children", childPath)
+			}
+
+			child.Size = c.Size
+		default:
+			return fmt.Errorf("%s: unknown type %q", childPath, c.Type)
+		}
+
+		parent.AddChild(child)
+
+		if err := importChildren(child, c, childPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func exportCommand(root *Node, args []string) error {
+	var format string
+
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.StringVar(&format, "format", "json", "output `format`, json or tree")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 0 {
+		return errors.New("usage: export [-format json|tree]")
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+
+	switch format {
+	case "json":
+		if err := writeJSON(w, root); err != nil {
+			return err
+		}
+	case "tree":
+		writeTree(w, root)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	return w.Flush()
+}
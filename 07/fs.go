@@ -0,0 +1,240 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NodeFS exposes a Node tree as a read-only filesystem. Files have the
+// size given by the transcript and are filled with zeroes.
+type NodeFS struct {
+	root *Node
+}
+
+var (
+	_ fs.FS        = NodeFS{}
+	_ fs.ReadDirFS = NodeFS{}
+	_ fs.StatFS    = NodeFS{}
+)
+
+func NewNodeFS(root *Node) NodeFS {
+	return NodeFS{root: root}
+}
+
+// lookup finds the node for a slash separated path relative to the root.
+func (fsys NodeFS) lookup(op, name string) (*Node, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	n := fsys.root
+
+	if name == "." {
+		return n, nil
+	}
+
+	for _, elem := range strings.Split(name, "/") {
+		if !n.IsDir {
+			return nil, &fs.PathError{
+				Op: op, Path: name, Err: errors.New("not a directory"),
+			}
+		}
+
+		child, ok := n.Children[elem]
+		if !ok {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+
+		n = child
+	}
+
+	return n, nil
+}
+
+func (fsys NodeFS) Open(name string) (fs.File, error) {
+	n, err := fsys.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.IsDir {
+		return &openDir{node: n, entries: dirEntries(n)}, nil
+	}
+
+	return &openFile{node: n}, nil
+}
+
+func (fsys NodeFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	n, err := fsys.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !n.IsDir {
+		return nil, &fs.PathError{
+			Op: "readdir", Path: name, Err: errors.New("not a directory"),
+		}
+	}
+
+	return dirEntries(n), nil
+}
+
+func (fsys NodeFS) Stat(name string) (fs.FileInfo, error) {
+	n, err := fsys.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return nodeInfo{n}, nil
+}
+
+// dirEntries returns the entries of a directory sorted by name.
+func dirEntries(n *Node) []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(n.Children))
+
+	for _, child := range n.Children {
+		entries = append(entries, fs.FileInfoToDirEntry(nodeInfo{child}))
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries
+}
+
+type nodeInfo struct {
+	node *Node
+}
+
+func (i nodeInfo) Name() string {
+	if i.node.Parent == nil {
+		return "."
+	}
+
+	return i.node.Name
+}
+
+func (i nodeInfo) Size() int64 {
+	if i.node.IsDir {
+		return 0
+	}
+
+	return int64(i.node.Size)
+}
+
+func (i nodeInfo) Mode() fs.FileMode {
+	if i.node.IsDir {
+		return fs.ModeDir | 0o555
+	}
+
+	return 0o444
+}
+
+func (i nodeInfo) ModTime() time.Time { return time.Time{} }
+func (i nodeInfo) IsDir() bool        { return i.node.IsDir }
+func (i nodeInfo) Sys() any           { return i.node }
+
+// openFile is a zero filled file. It implements io.Seeker and
+// io.ReaderAt so that it can be served by http.FileServer.
+type openFile struct {
+	node   *Node
+	offset int64
+}
+
+func (f *openFile) Stat() (fs.FileInfo, error) { return nodeInfo{f.node}, nil }
+func (f *openFile) Close() error               { return nil }
+
+func (f *openFile) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+
+	return n, err
+}
+
+func (f *openFile) ReadAt(p []byte, off int64) (int, error) {
+	size := int64(f.node.Size)
+
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.node.Name, Err: fs.ErrInvalid}
+	}
+
+	if off >= size {
+		return 0, io.EOF
+	}
+
+	// ReaderAt has to explain why it read less than asked for.
+	var err error
+
+	if rest := size - off; int64(len(p)) > rest {
+		p = p[:rest]
+		err = io.EOF
+	}
+
+	for i := range p {
+		p[i] = 0
+	}
+
+	return len(p), err
+}
+
+func (f *openFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(f.node.Size)
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.node.Name, Err: fs.ErrInvalid}
+	}
+
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.node.Name, Err: fs.ErrInvalid}
+	}
+
+	f.offset = offset
+
+	return offset, nil
+}
+
+type openDir struct {
+	node    *Node
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *openDir) Stat() (fs.FileInfo, error) { return nodeInfo{d.node}, nil }
+func (d *openDir) Close() error               { return nil }
+
+func (d *openDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{
+		Op: "read", Path: d.node.Name, Err: errors.New("is a directory"),
+	}
+}
+
+func (d *openDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+
+	if count <= 0 {
+		d.offset = len(d.entries)
+
+		return rest, nil
+	}
+
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+
+	if count > len(rest) {
+		count = len(rest)
+	}
+
+	d.offset += count
+
+	return rest[:count], nil
+}
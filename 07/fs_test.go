@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNodeFS(t *testing.T) {
+	f, err := os.Open("input.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	root, _, err := parseTranscript(f, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected []string
+
+	walkNodes(root, func(n *Node) {
+		if n.Parent != nil {
+			expected = append(expected, strings.TrimPrefix(nodePath(n), "/"))
+		}
+	})
+
+	if err := fstest.TestFS(NewNodeFS(root), expected...); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+type genOptions struct {
+	Order       string
+	Seed        int64
+	RedundantCd bool
+	RepeatLs    bool
+}
+
+// genEntry is a directory entry that can be represented in a transcript.
+type genEntry struct {
+	Name  string
+	IsDir bool
+	Size  int64
+}
+
+// generateTranscript writes a terminal transcript that explores fsys
+// with cd and ls, in the same format as the puzzle input.
+func generateTranscript(w io.Writer, fsys fs.FS, opts genOptions) error {
+	rnd := rand.New(rand.NewSource(opts.Seed))
+
+	fmt.Fprintln(w, "$ cd /")
+
+	var visit func(dir string, more bool) error
+
+	// more tells whether there is anything left to explore after dir,
+	// if there isn't we don't have to cd back out of it.
+	visit = func(dir string, more bool) error {
+		entries, err := readGenEntries(fsys, dir)
+		if err != nil {
+			return err
+		}
+
+		if err := orderEntries(entries, opts.Order, rnd); err != nil {
+			return err
+		}
+
+		listings := 1
+		if opts.RepeatLs {
+			listings = 2
+		}
+
+		for i := 0; i < listings; i++ {
+			fmt.Fprintln(w, "$ ls")
+
+			for _, e := range entries {
+				if e.IsDir {
+					fmt.Fprintf(w, "dir %s\n", e.Name)
+				} else {
+					fmt.Fprintf(w, "%d %s\n", e.Size, e.Name)
+				}
+			}
+		}
+
+		var dirs []genEntry
+
+		for _, e := range entries {
+			if e.IsDir {
+				dirs = append(dirs, e)
+			}
+		}
+
+		for i, d := range dirs {
+			childMore := more || i < len(dirs)-1
+
+			fmt.Fprintf(w, "$ cd %s\n", d.Name)
+
+			if err := visit(path.Join(dir, d.Name), childMore); err != nil {
+				return err
+			}
+
+			if childMore || opts.RedundantCd {
+				fmt.Fprintln(w, "$ cd ..")
+			}
+		}
+
+		return nil
+	}
+
+	if err := visit(".", false); err != nil {
+		return err
+	}
+
+	// We're back at the root, where cd .. is a no-op.
+	if opts.RedundantCd {
+		fmt.Fprintln(w, "$ cd ..")
+	}
+
+	return nil
+}
+
+// readGenEntries reads the directories and regular files in dir, other
+// kinds of files and names that can't be written on a transcript line
+// are skipped.
+func readGenEntries(fsys fs.FS, dir string) ([]genEntry, error) {
+	dirEntries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	var entries []genEntry
+
+	for _, de := range dirEntries {
+		name := de.Name()
+
+		if strings.ContainsAny(name, "\r\n/") || !de.Type().IsDir() && !de.Type().IsRegular() {
+			continue
+		}
+
+		e := genEntry{Name: name, IsDir: de.IsDir()}
+
+		if !e.IsDir {
+			info, err := de.Info()
+			if err != nil {
+				return nil, fmt.Errorf("failed to stat %q: %w",
+					path.Join(dir, name), err)
+			}
+
+			e.Size = info.Size()
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func orderEntries(entries []genEntry, order string, rnd *rand.Rand) error {
+	switch order {
+	case "name":
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name < entries[j].Name
+		})
+	case "reverse":
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name > entries[j].Name
+		})
+	case "size":
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Size > entries[j].Size
+		})
+	case "random":
+		rnd.Shuffle(len(entries), func(i, j int) {
+			entries[i], entries[j] = entries[j], entries[i]
+		})
+	default:
+		return fmt.Errorf("unknown order %q", order)
+	}
+
+	return nil
+}
+
+// fsSizes collects the size of every file and the recursive size of
+// every directory in fsys, keyed by path.
+func fsSizes(fsys fs.FS) (map[string]int64, error) {
+	sizes := make(map[string]int64)
+
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if strings.ContainsAny(d.Name(), "\r\n") || !d.Type().IsDir() && !d.Type().IsRegular() {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		if d.IsDir() {
+			sizes[p] += 0
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		sizes[p] = info.Size()
+
+		for dir := path.Dir(p); ; dir = path.Dir(dir) {
+			sizes[dir] += info.Size()
+
+			if dir == "." {
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sizes, nil
+}
+
+// verifyRoundTrip parses a transcript and checks that it reconstructs
+// the same sizes as fsys has.
+func verifyRoundTrip(fsys fs.FS, transcript []byte) (int, error) {
+	want, err := fsSizes(fsys)
+	if err != nil {
+		return 0, fmt.Errorf("failed to walk source tree: %w", err)
+	}
+
+	root, _, err := parseTranscript(bytes.NewReader(transcript), true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse generated transcript: %w", err)
+	}
+
+	got, err := fsSizes(NewNodeFS(root))
+	if err != nil {
+		return 0, fmt.Errorf("failed to walk parsed tree: %w", err)
+	}
+
+	var problems []string
+
+	for p, size := range want {
+		if g, ok := got[p]; !ok {
+			problems = append(problems, fmt.Sprintf("%s is missing", p))
+		} else if g != size {
+			problems = append(problems, fmt.Sprintf(
+				"%s has size %d, expected %d", p, g, size))
+		}
+	}
+
+	for p := range got {
+		if _, ok := want[p]; !ok {
+			problems = append(problems, fmt.Sprintf("%s shouldn't exist", p))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+
+		return 0, fmt.Errorf("round trip failed:\n  %s",
+			strings.Join(problems, "\n  "))
+	}
+
+	return len(want), nil
+}
+
+func genCommand(_ *Node, args []string) error {
+	var (
+		opts  genOptions
+		check bool
+	)
+
+	flags := flag.NewFlagSet("gen", flag.ContinueOnError)
+	flags.StringVar(&opts.Order, "order", "name",
+		"order of the entries: name, reverse, size or random")
+	flags.Int64Var(&opts.Seed, "seed", 1, "random seed for the random order")
+	flags.BoolVar(&opts.RedundantCd, "redundant-cd", false,
+		"cd .. out of every directory, even when it isn't needed")
+	flags.BoolVar(&opts.RepeatLs, "repeat-ls", false,
+		"list every directory twice")
+	flags.BoolVar(&check, "check", false,
+		"parse the generated transcript and verify that the sizes match")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: gen [flags] DIR")
+	}
+
+	fsys := os.DirFS(flags.Arg(0))
+
+	var buf bytes.Buffer
+
+	if err := generateTranscript(&buf, fsys, opts); err != nil {
+		return err
+	}
+
+	if check {
+		n, err := verifyRoundTrip(fsys, buf.Bytes())
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "round trip ok, %d paths match\n", n)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// genFixture has files of a few different sizes, an empty directory and
+// a deeply nested file. The sizes are kept small, as the data is
+// allocated for every test run.
+var genFixture = fstest.MapFS{
+	"b.txt":             {Data: make([]byte, 1485)},
+	"c.dat":             {Data: make([]byte, 850)},
+	"a/f":               {Data: make([]byte, 291)},
+	"a/g":               {Data: make([]byte, 25)},
+	"a/h.lst":           {Data: make([]byte, 625)},
+	"a/e/i":             {Data: make([]byte, 5)},
+	"d/j":               {Data: make([]byte, 406)},
+	"d/d.log":           {Data: make([]byte, 803)},
+	"d/d.ext":           {Data: make([]byte, 562)},
+	"d/k":               {Data: make([]byte, 721)},
+	"d/empty":           {Mode: fs.ModeDir},
+	"d/deep/er/est/x.y": {Data: []byte("hello")},
+	"z/zero":            {},
+}
+
+func TestGenerateRoundTrip(t *testing.T) {
+	want, err := fsSizes(genFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, order := range []string{"name", "reverse", "size", "random"} {
+		for _, redundantCd := range []bool{false, true} {
+			for _, repeatLs := range []bool{false, true} {
+				opts := genOptions{
+					Order:       order,
+					Seed:        7,
+					RedundantCd: redundantCd,
+					RepeatLs:    repeatLs,
+				}
+
+				name := fmt.Sprintf("%s/redundant-cd=%v/repeat-ls=%v",
+					order, redundantCd, repeatLs)
+
+				t.Run(name, func(t *testing.T) {
+					var buf bytes.Buffer
+
+					if err := generateTranscript(&buf, genFixture, opts); err != nil {
+						t.Fatal(err)
+					}
+
+					root, conflicts, err := parseTranscript(&buf, true)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if len(conflicts) > 0 {
+						t.Errorf("unexpected conflicts: %v", conflicts)
+					}
+
+					got, err := fsSizes(NewNodeFS(root))
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if !reflect.DeepEqual(got, want) {
+						t.Errorf("sizes after the round trip are %v, want %v", got, want)
+					}
+
+					if root.TotalSize() != int(want["."]) {
+						t.Errorf("total size is %d, want %d", root.TotalSize(), want["."])
+					}
+				})
+			}
+		}
+	}
+}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
 	"os"
-	"strconv"
-	"strings"
+	"sort"
 )
 
 func main() {
@@ -15,127 +19,202 @@ func main() {
 	}
 }
 
-var (
-	cmdCd = "$ cd "
-	cmdLs = "$ ls"
-)
-
-type Node struct {
-	Name     string
-	IsDir    bool
+// disk describes the device that the transcript was taken from.
+var disk = struct {
 	Size     int
-	Parent   *Node
-	Children map[string]*Node
+	Required int
+}{
+	Size:     70000000,
+	Required: 30000000,
 }
 
-func NewNode(parent *Node) *Node {
-	return &Node{
-		Parent:   parent,
-		Children: make(map[string]*Node),
+// strict makes conflicting listings in transcripts an error.
+var strict bool
+
+// command is a subcommand that operates on the reconstructed filesystem.
+type command struct {
+	Usage string
+	Run   func(root *Node, args []string) error
+	// Interactive commands read from stdin, so the transcript has to
+	// be read from a file.
+	Interactive bool
+	// NoTranscript commands don't operate on a transcript.
+	NoTranscript bool
+}
+
+var commands = map[string]command{
+	"export": {
+		Usage: "export [-format json|tree]\twrite the filesystem as JSON or a tree",
+		Run:   exportCommand,
+	},
+	"gen": {
+		Usage:        "gen [flags] DIR\tgenerate a transcript from a directory",
+		Run:          genCommand,
+		NoTranscript: true,
+	},
+	"glob": {
+		Usage: "glob PATTERN\tlist the paths matching a fs.Glob pattern",
+		Run:   globCommand,
+	},
+	"shell": {
+		Usage:       "shell\tinteractive shell with cd, ls, pwd, find, du and rm",
+		Run:         shellCommandFn,
+		Interactive: true,
+	},
+	"walk": {
+		Usage: "walk [PATH]\tlist every file and directory with its size",
+		Run:   walkCommand,
+	},
+	"diff": {
+		Usage:        "diff [-json] OLD NEW\tcompare two transcripts",
+		Run:          diffCommand,
+		NoTranscript: true,
+	},
+	"du": {
+		Usage: "du [-h] [-a] [-sort size|name] [-depth N]\tlist directory sizes",
+		Run:   duCommand,
+	},
+	"ncdu": {
+		Usage:       "ncdu\tbrowse directory sizes interactively",
+		Run:         ncduCommand,
+		Interactive: true,
+	},
+	"plan": {
+		Usage: "plan\tfind the directories to delete that free the least excess space",
+		Run:   planCommand,
+	},
+	"serve": {
+		Usage: "serve [-addr ADDR]\tserve the filesystem over HTTP",
+		Run:   serveCommand,
+	},
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+
+	fmt.Fprintf(out, "Usage: %s [flags] [command [args]]\n\n", os.Args[0])
+	fmt.Fprintln(out, "Without a command the puzzle answers are printed.")
+	fmt.Fprintln(out, "The input can be a transcript or a JSON export.")
+	fmt.Fprintln(out, "\nCommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(out, "  %s\n", commands[name].Usage)
 	}
+
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
 }
 
 func run() error {
-	var linum int
-
-	root := NewNode(nil)
-	root.IsDir = true
-
-	cwd := root
-
-	r := bufio.NewScanner(os.Stdin)
-
-	for r.Scan() {
-		line := r.Text()
-
-		linum++
-
-		if strings.HasPrefix(line, "$ ") {
-			cmd, args, _ := strings.Cut(line[2:], " ")
-
-			switch cmd {
-			case "cd":
-				if args == "" {
-					return fmt.Errorf(
-						"missing argument for cd on line %d",
-						linum)
-				}
-
-				path := strings.Split(args, "/")
-
-				for i, dir := range path {
-					if i == 0 && dir == "" {
-						cwd = root
-						continue
-					}
-
-					if dir == "." {
-						continue
-					}
-
-					if dir == ".." {
-						if cwd.Parent != nil {
-							cwd = cwd.Parent
-						}
-						continue
-					}
-
-					child := cwd.Children[dir]
-					if child == nil {
-						child = NewNode(cwd)
-						cwd.Children[dir] = child
-					}
-
-					cwd = child
-				}
-			case "ls":
-				// We'll just ignore the actual command and
-				// accept ls results to cwd as they come in
-			default:
-				return fmt.Errorf("unknown command %q on line %d",
-					cmd, linum)
-
-			}
-		} else {
-			ds, name, ok := strings.Cut(line, " ")
-			if !ok {
-				return fmt.Errorf("invalid dir entry %q on line %d",
-					line, linum)
-			}
-
-			child := NewNode(cwd)
-			child.Name = name
-
-			if ds == "dir" {
-				child.IsDir = true
-			} else {
-				size, err := strconv.Atoi(ds)
-				if err != nil {
-					return fmt.Errorf(
-						"invalid file size %q on line %d",
-						line, linum)
-				}
-
-				child.Size = size
-			}
-
-			cwd.Children[name] = child
+	var transcript string
+
+	flag.StringVar(&transcript, "f", "",
+		"read the transcript from `file` instead of stdin")
+	flag.IntVar(&disk.Size, "disk", disk.Size, "total disk size in bytes")
+	flag.IntVar(&disk.Required, "need", disk.Required,
+		"free space in bytes that is needed for the update")
+	flag.BoolVar(&strict, "strict", false,
+		"fail on listings that conflict with earlier ones, rather than warning")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		root, err := loadTranscript(transcript)
+		if err != nil {
+			return err
 		}
+
+		return answer(root)
 	}
 
-	if err := r.Err(); err != nil {
-		return fmt.Errorf("failed to read stdin: %w", err)
+	name := flag.Arg(0)
+
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q", name)
 	}
 
-	var sum int
+	if cmd.NoTranscript {
+		return cmd.Run(nil, flag.Args()[1:])
+	}
 
-	walkNodes(root, func(n *Node) {
-		if n.Parent != nil {
-			n.Parent.Size += n.Size
+	if cmd.Interactive && transcript == "" {
+		return fmt.Errorf("%s reads from stdin, use -f to give the transcript", name)
+	}
+
+	root, err := loadTranscript(transcript)
+	if err != nil {
+		return err
+	}
+
+	return cmd.Run(root, flag.Args()[1:])
+}
+
+func loadTranscript(path string) (*Node, error) {
+	var r io.Reader = os.Stdin
+
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open transcript: %w", err)
+		}
+
+		defer f.Close()
+
+		r = f
+	}
+
+	br := bufio.NewReader(r)
+
+	// Transcripts start with a command, so anything that looks like a
+	// JSON object is a JSON export.
+	if isJSON(br) {
+		return readJSON(br)
+	}
+
+	root, conflicts, err := parseTranscript(br, strict)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range conflicts {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", c)
+	}
+
+	return root, nil
+}
+
+func isJSON(r *bufio.Reader) bool {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return false
 		}
 
-		if n.IsDir && n.Size <= 100000 {
-			sum += n.Size
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+
+		_ = r.UnreadByte()
+
+		return b == '{'
+	}
+}
+
+func answer(root *Node) error {
+	var sum int
+
+	walkNodes(root, func(n *Node) {
+		if n.IsDir && n.TotalSize() <= 100000 {
+			sum += n.TotalSize()
 		}
 	})
 
@@ -143,16 +222,16 @@ func run() error {
 
 	var justRightSize int
 
-	freeSpace := 70000000 - root.Size
-	freeUp := 30000000 - freeSpace
+	freeSpace := disk.Size - root.TotalSize()
+	freeUp := disk.Required - freeSpace
 
 	walkNodes(root, func(n *Node) {
 		if !n.IsDir {
 			return
 		}
 
-		if n.Size >= freeUp && (justRightSize == 0 || n.Size < justRightSize) {
-			justRightSize = n.Size
+		if n.TotalSize() >= freeUp && (justRightSize == 0 || n.TotalSize() < justRightSize) {
+			justRightSize = n.TotalSize()
 		}
 	})
 
@@ -161,10 +240,67 @@ func run() error {
 	return nil
 }
 
-func walkNodes(n *Node, fn func(n *Node)) {
-	for k := range n.Children {
-		walkNodes(n.Children[k], fn)
+func globCommand(root *Node, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: glob PATTERN")
+	}
+
+	matches, err := fs.Glob(NewNodeFS(root), args[0])
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	for _, m := range matches {
+		fmt.Fprintln(os.Stdout, m)
+	}
+
+	return nil
+}
+
+func walkCommand(root *Node, args []string) error {
+	dir := "."
+
+	switch len(args) {
+	case 0:
+	case 1:
+		dir = args[0]
+	default:
+		return errors.New("usage: walk [PATH]")
+	}
+
+	fsys := NewNodeFS(root)
+
+	return fs.WalkDir(fsys, dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			fmt.Fprintf(os.Stdout, "%s/\n", path)
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stdout, "%s %d\n", path, info.Size())
+
+		return nil
+	})
+}
+
+func serveCommand(root *Node, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", "localhost:8007", "`address` to listen on")
+
+	if err := flags.Parse(args); err != nil {
+		return err
 	}
 
-	fn(n)
+	println("serving the filesystem on http://" + *addr)
+
+	return http.ListenAndServe(*addr,
+		http.FileServer(http.FS(NewNodeFS(root))))
 }
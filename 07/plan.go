@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// maxPlanMemory limits the memory used for the reachable sums of the
+// deletion planner.
+const maxPlanMemory = 1 << 30
+
+// deletionPlan is a set of directories, none of them inside another,
+// that together free up enough space.
+type deletionPlan struct {
+	Dirs  []*Node
+	Total int
+}
+
+// smallestSingleDir finds the smallest directory that frees up at least
+// need bytes, it returns nil if there's none.
+func smallestSingleDir(root *Node, need int) *Node {
+	var best *Node
+
+	walkNodes(root, func(n *Node) {
+		if !n.IsDir || n.TotalSize() < need {
+			return
+		}
+
+		if best == nil || n.TotalSize() < best.TotalSize() {
+			best = n
+		}
+	})
+
+	return best
+}
+
+// planDeletion finds the set of non-nested directories whose combined
+// size is at least need, while being as small as possible. The sizes
+// that can be reached are tracked with bitsets, visiting the directories
+// in preorder: a directory is either skipped, which moves on to its
+// first child, or deleted, which adds its size and skips its subtree.
+// Sums above the upper bound, the best single directory, are dropped.
+func planDeletion(root *Node, need, bound int) (*deletionPlan, error) {
+	var (
+		dirs []*Node
+		end  []int
+	)
+
+	var visit func(n *Node)
+
+	visit = func(n *Node) {
+		i := len(dirs)
+
+		dirs = append(dirs, n)
+		end = append(end, 0)
+
+		for _, c := range sortedChildren(n, false) {
+			if c.IsDir {
+				visit(c)
+			}
+		}
+
+		end[i] = len(dirs)
+	}
+
+	visit(root)
+
+	words := bound/64 + 1
+
+	if (len(dirs)+1)*words*8 > maxPlanMemory {
+		return nil, fmt.Errorf(
+			"too many combinations to search: %d directories and sums up to %d",
+			len(dirs), bound)
+	}
+
+	reach := make([][]uint64, len(dirs)+1)
+	for i := range reach {
+		reach[i] = make([]uint64, words)
+	}
+
+	reach[0][0] = 1
+
+	for i, d := range dirs {
+		orBits(reach[i+1], reach[i], 0, bound)
+		orBits(reach[end[i]], reach[i], d.TotalSize(), bound)
+	}
+
+	total := -1
+
+	for s := need; s <= bound; s++ {
+		if hasBit(reach[len(dirs)], s) {
+			total = s
+			break
+		}
+	}
+
+	if total == -1 {
+		return nil, errors.New("no combination of directories frees enough space")
+	}
+
+	plan := deletionPlan{Total: total}
+
+	// Walk back through the decisions that led to the total.
+	for j, s := len(dirs), total; j > 0; {
+		taken := false
+
+		for i := j - 1; i >= 0; i-- {
+			size := dirs[i].TotalSize()
+
+			if end[i] == j && s >= size && hasBit(reach[i], s-size) {
+				plan.Dirs = append(plan.Dirs, dirs[i])
+				j, s = i, s-size
+				taken = true
+
+				break
+			}
+		}
+
+		if !taken {
+			j--
+		}
+	}
+
+	sort.Slice(plan.Dirs, func(i, j int) bool {
+		return plan.Dirs[i].TotalSize() > plan.Dirs[j].TotalSize()
+	})
+
+	return &plan, nil
+}
+
+func hasBit(set []uint64, i int) bool {
+	return set[i/64]&(1<<(i%64)) != 0
+}
+
+// orBits sets dst to dst | src<<shift, ignoring bits above limit.
+func orBits(dst, src []uint64, shift, limit int) {
+	if shift > limit {
+		return
+	}
+
+	wordShift, bitShift := shift/64, uint(shift%64)
+
+	for i := len(dst) - 1; i >= wordShift; i-- {
+		v := src[i-wordShift] << bitShift
+
+		if bitShift > 0 && i-wordShift > 0 {
+			v |= src[i-wordShift-1] >> (64 - bitShift)
+		}
+
+		dst[i] |= v
+	}
+
+	// Clear anything past the limit in the last word.
+	if rest := (limit + 1) % 64; rest != 0 {
+		dst[len(dst)-1] &= 1<<rest - 1
+	}
+}
+
+func planCommand(root *Node, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: plan")
+	}
+
+	used := root.TotalSize()
+	free := disk.Size - used
+	need := disk.Required - free
+
+	fmt.Printf("disk size %d, used %d, free %d, required %d\n",
+		disk.Size, used, free, disk.Required)
+
+	if need <= 0 {
+		fmt.Println("there already is enough free space")
+		return nil
+	}
+
+	fmt.Printf("need to free %d bytes\n\n", need)
+
+	single := smallestSingleDir(root, need)
+	if single == nil {
+		return errors.New("deleting everything doesn't free enough space")
+	}
+
+	fmt.Printf("single directory: %s, deletes %d bytes (%d more than needed)\n",
+		nodePath(single), single.TotalSize(), single.TotalSize()-need)
+
+	plan, err := planDeletion(root, need, single.TotalSize())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("best plan: %d directories, deletes %d bytes (%d more than needed, %d less than the single directory)\n",
+		len(plan.Dirs), plan.Total, plan.Total-need, single.TotalSize()-plan.Total)
+
+	for _, d := range plan.Dirs {
+		fmt.Fprintf(os.Stdout, "%10d %s\n", d.TotalSize(), nodePath(d))
+	}
+
+	return nil
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// shell is an interactive session over a reconstructed filesystem.
+type shell struct {
+	root *Node
+	cwd  *Node
+	out  io.Writer
+}
+
+type shellCommand func(sh *shell, args []string) error
+
+var shellCommands = map[string]shellCommand{
+	"cd":   (*shell).cd,
+	"ls":   (*shell).ls,
+	"pwd":  (*shell).pwd,
+	"find": (*shell).find,
+	"du":   (*shell).du,
+	"rm":   (*shell).rm,
+	"help": (*shell).help,
+}
+
+func shellCommandFn(root *Node, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: shell")
+	}
+
+	return runShell(os.Stdin, os.Stdout, root)
+}
+
+func runShell(in io.Reader, out io.Writer, root *Node) error {
+	sh := shell{
+		root: root,
+		cwd:  root,
+		out:  out,
+	}
+
+	s := bufio.NewScanner(in)
+
+	for {
+		fmt.Fprintf(out, "%s$ ", nodePath(sh.cwd))
+
+		if !s.Scan() {
+			fmt.Fprintln(out)
+			return s.Err()
+		}
+
+		args := strings.Fields(s.Text())
+		if len(args) == 0 {
+			continue
+		}
+
+		if args[0] == "exit" || args[0] == "quit" {
+			return nil
+		}
+
+		cmd, ok := shellCommands[args[0]]
+		if !ok {
+			fmt.Fprintf(out, "%s: command not found\n", args[0])
+			continue
+		}
+
+		if err := cmd(&sh, args[1:]); err != nil {
+			fmt.Fprintf(out, "%s: %v\n", args[0], err)
+		}
+	}
+}
+
+func (sh *shell) help(args []string) error {
+	fmt.Fprint(sh.out, `cd PATH                 change directory
+ls [PATH]               list a directory
+pwd                     print the current directory
+find [PATH] [-type d|f] [-size [+|-]N]
+                        find files and directories by total size
+du [-h] [-a] [-depth N] [PATH]
+                        list directory sizes
+rm [-r] PATH            delete a file or directory
+exit                    leave the shell
+`)
+
+	return nil
+}
+
+func (sh *shell) lookup(args []string) (*Node, error) {
+	switch len(args) {
+	case 0:
+		return sh.cwd, nil
+	case 1:
+		return lookupNode(sh.root, sh.cwd, args[0])
+	}
+
+	return nil, errors.New("too many arguments")
+}
+
+func (sh *shell) cd(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: cd PATH")
+	}
+
+	n, err := lookupNode(sh.root, sh.cwd, args[0])
+	if err != nil {
+		return err
+	}
+
+	if !n.IsDir {
+		return fmt.Errorf("%s: not a directory", nodePath(n))
+	}
+
+	sh.cwd = n
+
+	return nil
+}
+
+func (sh *shell) ls(args []string) error {
+	n, err := sh.lookup(args)
+	if err != nil {
+		return err
+	}
+
+	if !n.IsDir {
+		fmt.Fprintf(sh.out, "%d %s\n", n.Size, n.Name)
+		return nil
+	}
+
+	for _, c := range sortedChildren(n, false) {
+		if c.IsDir {
+			fmt.Fprintf(sh.out, "dir %s\n", c.Name)
+		} else {
+			fmt.Fprintf(sh.out, "%d %s\n", c.Size, c.Name)
+		}
+	}
+
+	return nil
+}
+
+func (sh *shell) pwd(args []string) error {
+	fmt.Fprintln(sh.out, nodePath(sh.cwd))
+
+	return nil
+}
+
+// sizeFilter parses find's -size argument, a leading "+" matches larger
+// sizes and a leading "-" smaller ones.
+func sizeFilter(s string) (func(size int) bool, error) {
+	var cmp byte
+
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		cmp, s = s[0], s[1:]
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid size %q", s)
+	}
+
+	switch cmp {
+	case '+':
+		return func(size int) bool { return size > n }, nil
+	case '-':
+		return func(size int) bool { return size < n }, nil
+	}
+
+	return func(size int) bool { return size == n }, nil
+}
+
+func (sh *shell) find(args []string) error {
+	var (
+		start = sh.cwd
+		match = func(size int) bool { return true }
+		kind  string
+	)
+
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-size" || arg == "-type":
+			if i+1 == len(args) {
+				return fmt.Errorf("missing value for %s", arg)
+			}
+
+			i++
+
+			if arg == "-type" {
+				kind = args[i]
+
+				if kind != "d" && kind != "f" {
+					return fmt.Errorf("invalid type %q", kind)
+				}
+
+				continue
+			}
+
+			fn, err := sizeFilter(args[i])
+			if err != nil {
+				return err
+			}
+
+			match = fn
+		case i == 0 && !strings.HasPrefix(arg, "-"):
+			n, err := lookupNode(sh.root, sh.cwd, arg)
+			if err != nil {
+				return err
+			}
+
+			start = n
+		default:
+			return fmt.Errorf("unknown argument %q", arg)
+		}
+	}
+
+	var visit func(n *Node)
+
+	visit = func(n *Node) {
+		if match(n.TotalSize()) && (kind == "" || (kind == "d") == n.IsDir) {
+			fmt.Fprintf(sh.out, "%10d %s\n", n.TotalSize(), nodePath(n))
+		}
+
+		for _, c := range sortedChildren(n, false) {
+			visit(c)
+		}
+	}
+
+	visit(start)
+
+	return nil
+}
+
+func (sh *shell) du(args []string) error {
+	opts := duOptions{BySize: true}
+
+	flags := flag.NewFlagSet("du", flag.ContinueOnError)
+	flags.SetOutput(sh.out)
+	flags.BoolVar(&opts.Human, "h", false, "print sizes in human readable units")
+	flags.BoolVar(&opts.All, "a", false, "list files as well as directories")
+	flags.IntVar(&opts.MaxDepth, "depth", 1, "only list entries down to this depth, -1 for no limit")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	n, err := sh.lookup(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	printDu(sh.out, n, opts)
+
+	return nil
+}
+
+func (sh *shell) rm(args []string) error {
+	var recursive bool
+
+	flags := flag.NewFlagSet("rm", flag.ContinueOnError)
+	flags.SetOutput(sh.out)
+	flags.BoolVar(&recursive, "r", false, "remove directories and their contents")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: rm [-r] PATH")
+	}
+
+	n, err := lookupNode(sh.root, sh.cwd, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if n.Parent == nil {
+		return errors.New("refusing to remove /")
+	}
+
+	if n.IsDir && !recursive {
+		return fmt.Errorf("%s: is a directory", nodePath(n))
+	}
+
+	for c := sh.cwd; c != nil; c = c.Parent {
+		if c == n {
+			return errors.New("can't remove the current directory")
+		}
+	}
+
+	n.Parent.RemoveChild(n.Name)
+
+	sh.printCandidates()
+
+	return nil
+}
+
+// printCandidates re-evaluates which single directory to delete to make
+// room for the update.
+func (sh *shell) printCandidates() {
+	used := sh.root.TotalSize()
+	free := disk.Size - used
+	need := disk.Required - free
+
+	fmt.Fprintf(sh.out, "used %d, free %d, required %d\n",
+		used, free, disk.Required)
+
+	if need <= 0 {
+		fmt.Fprintln(sh.out, "there is enough free space for the update")
+		return
+	}
+
+	best := smallestSingleDir(sh.root, need)
+	if best == nil {
+		fmt.Fprintf(sh.out, "need %d more bytes, no directory is large enough\n", need)
+		return
+	}
+
+	fmt.Fprintf(sh.out, "need %d more bytes, smallest directory to delete: %s (%d bytes)\n",
+		need, nodePath(best), best.TotalSize())
+}
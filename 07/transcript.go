@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	cmdCd = "$ cd "
+	cmdLs = "$ ls"
+)
+
+// Node is a file or directory. Size is the size of the node itself,
+// which is zero for directories, use TotalSize to get the size including
+// the children. The total is cached, so the tree should be changed
+// through SetSize, AddChild and RemoveChild which invalidate it.
+type Node struct {
+	Name     string
+	IsDir    bool
+	Size     int
+	Parent   *Node
+	Children map[string]*Node
+
+	total      int
+	totalValid bool
+}
+
+func NewNode(parent *Node) *Node {
+	return &Node{
+		Parent:   parent,
+		Children: make(map[string]*Node),
+	}
+}
+
+// TotalSize returns the size of the node and everything below it.
+func (n *Node) TotalSize() int {
+	if n.totalValid {
+		return n.total
+	}
+
+	total := n.Size
+
+	for _, c := range n.Children {
+		total += c.TotalSize()
+	}
+
+	n.total = total
+	n.totalValid = true
+
+	return total
+}
+
+// invalidate drops the cached totals of the node and its ancestors.
+func (n *Node) invalidate() {
+	for ; n != nil && n.totalValid; n = n.Parent {
+		n.totalValid = false
+	}
+}
+
+func (n *Node) SetSize(size int) {
+	n.Size = size
+	n.invalidate()
+}
+
+// AddChild adds child to n, replacing any existing child with the same
+// name.
+func (n *Node) AddChild(child *Node) {
+	child.Parent = n
+	n.Children[child.Name] = child
+	n.invalidate()
+}
+
+func (n *Node) RemoveChild(name string) {
+	child, ok := n.Children[name]
+	if !ok {
+		return
+	}
+
+	delete(n.Children, name)
+	child.Parent = nil
+	n.invalidate()
+}
+
+// Conflict is a line of a transcript that disagrees with what an
+// earlier line said about the same path.
+type Conflict struct {
+	Line     int
+	PrevLine int
+	Path     string
+	Msg      string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("line %d: %s %s, as of line %d",
+		c.Line, c.Path, c.Msg, c.PrevLine)
+}
+
+// ConflictError is returned by parseTranscript in strict mode when the
+// transcript contradicts itself.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	lines := make([]string, len(e.Conflicts))
+
+	for i, c := range e.Conflicts {
+		lines[i] = c.String()
+	}
+
+	return fmt.Sprintf("%d conflicting listings:\n  %s",
+		len(lines), strings.Join(lines, "\n  "))
+}
+
+// parseTranscript reconstructs the filesystem from a terminal transcript
+// of cd and ls commands. Directories that are listed more than once,
+// or listed after they have been entered, keep their contents. The
+// returned conflicts are the listings that contradict earlier ones, in
+// which case the latest listing wins, and entries that a directory
+// listing leaves out, which are kept. In strict mode conflicts are
+// returned as a *ConflictError instead.
+func parseTranscript(r io.Reader, strict bool) (*Node, []Conflict, error) {
+	var (
+		linum     int
+		conflicts []Conflict
+	)
+
+	root := NewNode(nil)
+	root.IsDir = true
+
+	// seenOn keeps track of the line where we last learned about a
+	// node, for conflict reports.
+	seenOn := map[*Node]int{root: 0}
+
+	conflictAt := func(line int, n *Node, format string, a ...any) {
+		conflicts = append(conflicts, Conflict{
+			Line:     line,
+			PrevLine: seenOn[n],
+			Path:     nodePath(n),
+			Msg:      fmt.Sprintf(format, a...),
+		})
+	}
+
+	conflict := func(n *Node, format string, a ...any) {
+		conflictAt(linum, n, format, a...)
+	}
+
+	// The directory being listed, the line of the ls command, and the
+	// names that the listing has included so far. listedOn is the line
+	// of the last complete listing of each directory.
+	var (
+		listing  *Node
+		lsLine   int
+		included map[string]bool
+	)
+
+	listedOn := map[*Node]int{}
+
+	// endListing reports the entries of an earlier listing that are
+	// missing from the one that just ended.
+	endListing := func() {
+		if listing == nil {
+			return
+		}
+
+		if _, listedBefore := listedOn[listing]; listedBefore {
+			for _, c := range sortedChildren(listing, false) {
+				if !included[c.Name] {
+					conflictAt(lsLine, c, "is missing from the listing")
+				}
+			}
+		}
+
+		listedOn[listing] = lsLine
+		listing = nil
+	}
+
+	// entry returns the child called name, replacing any existing
+	// child of a different kind.
+	entry := func(parent *Node, name string, isDir bool) *Node {
+		child := parent.Children[name]
+
+		if child != nil && child.IsDir != isDir {
+			if isDir {
+				conflict(child, "is a directory, but was a file")
+			} else {
+				conflict(child, "is a file, but was a directory")
+			}
+
+			child = nil
+		}
+
+		if child == nil {
+			child = NewNode(parent)
+			child.Name = name
+			child.IsDir = isDir
+			parent.AddChild(child)
+		}
+
+		seenOn[child] = linum
+
+		return child
+	}
+
+	cwd := root
+
+	s := bufio.NewScanner(r)
+
+	for s.Scan() {
+		line := s.Text()
+
+		linum++
+
+		if strings.HasPrefix(line, "$ ") {
+			cmd, args, _ := strings.Cut(line[2:], " ")
+
+			endListing()
+
+			switch cmd {
+			case "cd":
+				if args == "" {
+					return nil, nil, fmt.Errorf(
+						"missing argument for cd on line %d",
+						linum)
+				}
+
+				var err error
+
+				cwd, err = changeDir(root, cwd, args,
+					func(dir *Node, name string) (*Node, error) {
+						return entry(dir, name, true), nil
+					})
+				if err != nil {
+					return nil, nil, fmt.Errorf("%v on line %d", err, linum)
+				}
+			case "ls":
+				// We'll just ignore any arguments and accept
+				// ls results to cwd as they come in.
+				listing = cwd
+				lsLine = linum
+				included = map[string]bool{}
+			default:
+				return nil, nil, fmt.Errorf("unknown command %q on line %d",
+					cmd, linum)
+
+			}
+		} else {
+			ds, name, ok := strings.Cut(line, " ")
+			if !ok {
+				return nil, nil, fmt.Errorf("invalid dir entry %q on line %d",
+					line, linum)
+			}
+
+			if listing == cwd {
+				included[name] = true
+			}
+
+			if ds == "dir" {
+				entry(cwd, name, true)
+				continue
+			}
+
+			size, err := strconv.Atoi(ds)
+			if err != nil {
+				return nil, nil, fmt.Errorf(
+					"invalid file size %q on line %d",
+					line, linum)
+			}
+
+			existing := cwd.Children[name]
+			if existing != nil && !existing.IsDir && existing.Size != size {
+				conflict(existing, "has size %d, but had size %d",
+					size, existing.Size)
+			}
+
+			entry(cwd, name, false).SetSize(size)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	endListing()
+
+	// Missing entries are only found at the end of a listing.
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		return conflicts[i].Line < conflicts[j].Line
+	})
+
+	if strict && len(conflicts) > 0 {
+		return nil, nil, &ConflictError{Conflicts: conflicts}
+	}
+
+	return root, conflicts, nil
+}
+
+// changeDir follows a cd path from cwd and returns the directory it ends
+// up in. Absolute paths start at root, empty and "." elements are
+// skipped, and ".." stops at the root. Every other element is resolved
+// by enter, which gets the current directory and the element name.
+func changeDir(
+	root, cwd *Node, path string,
+	enter func(dir *Node, name string) (*Node, error),
+) (*Node, error) {
+	for i, dir := range strings.Split(path, "/") {
+		if i == 0 && dir == "" {
+			cwd = root
+			continue
+		}
+
+		// Skip the empty elements of paths like "/" and "a//b".
+		if dir == "" || dir == "." {
+			continue
+		}
+
+		if dir == ".." {
+			if cwd.Parent != nil {
+				cwd = cwd.Parent
+			}
+			continue
+		}
+
+		next, err := enter(cwd, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		cwd = next
+	}
+
+	return cwd, nil
+}
+
+// lookupNode resolves a path like changeDir does, but only to existing
+// nodes. The last element may be a file.
+func lookupNode(root, cwd *Node, path string) (*Node, error) {
+	return changeDir(root, cwd, path, func(dir *Node, name string) (*Node, error) {
+		if !dir.IsDir {
+			return nil, fmt.Errorf("%s: not a directory", nodePath(dir))
+		}
+
+		child, ok := dir.Children[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: no such file or directory",
+				strings.TrimSuffix(nodePath(dir), "/")+"/"+name)
+		}
+
+		return child, nil
+	})
+}
+
+func walkNodes(n *Node, fn func(n *Node)) {
+	for k := range n.Children {
+		walkNodes(n.Children[k], fn)
+	}
+
+	fn(n)
+}
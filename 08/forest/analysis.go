@@ -0,0 +1,243 @@
+package forest
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Visibility is a bitmask of the directions that a tree can be seen
+// from outside the forest, bit i being set if the tree is visible when
+// looking in the i:th direction of the analysis.
+type Visibility uint32
+
+// The bits for the Orthogonal directions.
+const (
+	Hidden        Visibility = 0
+	VisibleTop    Visibility = 1
+	VisibleRight  Visibility = 1 << 1
+	VisibleBottom Visibility = 1 << 2
+	VisibleLeft   Visibility = 1 << 3
+)
+
+// Analysis holds the visibility and scenic score of every tree in a
+// grid, indexed the same way as Grid.Heights.
+type Analysis struct {
+	Width      int
+	Height     int
+	Directions []Direction
+	Visible    []Visibility
+	Scores     []int
+
+	grid *Grid
+}
+
+// Analyze works out the visibility and scenic score of every tree in the
+// grid, looking in the Orthogonal directions.
+func Analyze(g *Grid) (*Analysis, error) {
+	return AnalyzeDirections(g, Orthogonal)
+}
+
+// AnalyzeDirections works out the visibility and scenic score of every
+// tree in the grid, looking in the given directions. Each direction is
+// handled in O(width*height) time. An error is returned if the scores
+// could get too large for an int.
+func AnalyzeDirections(g *Grid, dirs []Direction) (*Analysis, error) {
+	if err := checkDirections(dirs); err != nil {
+		return nil, err
+	}
+
+	if err := checkScoreRange(g, dirs); err != nil {
+		return nil, err
+	}
+
+	n := g.Width * g.Height
+
+	a := Analysis{
+		Width:      g.Width,
+		Height:     g.Height,
+		Directions: dirs,
+		Visible:    make([]Visibility, n),
+		Scores:     make([]int, n),
+		grid:       g,
+	}
+
+	for i := range a.Scores {
+		a.Scores[i] = 1
+	}
+
+	links := make([]int32, n)
+
+	for i, d := range dirs {
+		a.look(g.Heights, links, d, 1<<i)
+	}
+
+	return &a, nil
+}
+
+// checkScoreRange returns an error if a tree in the grid could get a
+// scenic score that doesn't fit in an int. Nothing can be seen past the
+// edge of the forest, so the highest possible score for a tree is the
+// product of the number of trees between it and the edge in each
+// direction. The longest view in each direction is checked first, as
+// that is cheap and usually enough to rule out an overflow.
+func checkScoreRange(g *Grid, dirs []Direction) error {
+	if g.Width == 0 || g.Height == 0 {
+		return nil
+	}
+
+	longest := 1
+
+	for _, d := range dirs {
+		x, y := 0, 0
+
+		if d.DX < 0 {
+			x = g.Width - 1
+		}
+
+		if d.DY < 0 {
+			y = g.Height - 1
+		}
+
+		longest = mulScore(longest, d.steps(x, y, g.Width, g.Height))
+	}
+
+	if longest < math.MaxInt {
+		return nil
+	}
+
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			score := 1
+
+			for _, d := range dirs {
+				score = mulScore(score, d.steps(x, y, g.Width, g.Height))
+			}
+
+			if score == math.MaxInt {
+				return fmt.Errorf("the scenic score of tree %d,%d could be too large to count, "+
+					"use a smaller forest or fewer directions", x, y)
+			}
+		}
+	}
+
+	return nil
+}
+
+// mulScore multiplies two non-negative scores, saturating at
+// math.MaxInt rather than overflowing.
+func mulScore(a, b int) int {
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	if hi != 0 || lo >= math.MaxInt {
+		return math.MaxInt
+	}
+
+	return int(lo)
+}
+
+// look works out how far every tree can see in direction d. The trees
+// are visited in an order where the next tree in that direction has
+// always been visited before, and links[i] is set to the number of
+// steps from tree i to the closest tree in direction d that is at least
+// as tall, or 0 if there is none. Trees shorter than tree i can't block
+// the view from trees behind it either, so when looking past a tree the
+// search can jump straight to the tree that blocks its view. This works
+// like a stack of the trees that are still in view, but lets the rows be
+// read in order whatever the direction.
+func (a *Analysis) look(heights []int, links []int32, d Direction, bit Visibility) {
+	w, h := a.Width, a.Height
+	offset := d.DY*w + d.DX
+
+	y0, y1, ystep := 0, h, 1
+	if d.DY > 0 {
+		y0, y1, ystep = h-1, -1, -1
+	}
+
+	x0, x1, xstep := 0, w, 1
+	if d.DX > 0 {
+		x0, x1, xstep = w-1, -1, -1
+	}
+
+	for y := y0; y != y1; y += ystep {
+		nextY := y + d.DY
+		rowInView := nextY >= 0 && nextY < h
+
+		for x := x0; x != x1; x += xstep {
+			i := y*w + x
+			tree := heights[i]
+			nextX := x + d.DX
+
+			var steps int
+
+			if rowInView && nextX >= 0 && nextX < w {
+				steps = 1
+
+				for j := i + offset; heights[j] < tree; j = i + steps*offset {
+					link := int(links[j])
+					if link == 0 {
+						steps = 0
+						break
+					}
+
+					steps += link
+				}
+			}
+
+			links[i] = int32(steps)
+
+			if steps == 0 {
+				a.Visible[i] |= bit
+				a.Scores[i] *= d.steps(x, y, w, h)
+
+				continue
+			}
+
+			a.Scores[i] *= steps
+		}
+	}
+}
+
+// VisibleCount returns the number of trees that can be seen from
+// outside the forest in at least one direction.
+func (a *Analysis) VisibleCount() int {
+	var count int
+
+	for _, v := range a.Visible {
+		if v != Hidden {
+			count++
+		}
+	}
+
+	return count
+}
+
+// VisibleFrom returns the directions that v is set for.
+func (a *Analysis) VisibleFrom(v Visibility) []Direction {
+	dirs := make([]Direction, 0, bits.OnesCount32(uint32(v)))
+
+	for i, d := range a.Directions {
+		if v&(1<<i) != 0 {
+			dirs = append(dirs, d)
+		}
+	}
+
+	return dirs
+}
+
+// Best returns the position and score of the tree with the highest
+// scenic score. Ties go to the first tree in row order.
+func (a *Analysis) Best() (x, y, score int) {
+	best := -1
+
+	for i, s := range a.Scores {
+		if best == -1 || s > a.Scores[best] {
+			best = i
+		}
+	}
+
+	if best == -1 {
+		return 0, 0, 0
+	}
+
+	return best % a.Width, best / a.Width, a.Scores[best]
+}
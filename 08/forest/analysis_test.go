@@ -0,0 +1,215 @@
+package forest
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func randomGrid(width, height, maxHeight int, seed int64) *Grid {
+	rnd := rand.New(rand.NewSource(seed))
+
+	g := NewGrid(width, height)
+
+	for i := range g.Heights {
+		g.Heights[i] = rnd.Intn(maxHeight + 1)
+	}
+
+	return g
+}
+
+// flatGrid creates a forest where every tree has the same height.
+func flatGrid(width, height, h int) *Grid {
+	g := NewGrid(width, height)
+
+	for i := range g.Heights {
+		g.Heights[i] = h
+	}
+
+	return g
+}
+
+// ridgeGrid creates a forest that gets taller towards the middle, so
+// that most trees can see all the way to the edges.
+func ridgeGrid(width, height int) *Grid {
+	g := NewGrid(width, height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			g.Heights[y*width+x] = min(x, width-1-x) + min(y, height-1-y)
+		}
+	}
+
+	return g
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// visibleFrom looks outwards from p in direction d, the way the
+// original solutions did, and reports whether the edge can be seen.
+func visibleFrom(g *Grid, p Point, d Direction) bool {
+	h := g.At(p.X, p.Y)
+
+	for x, y := p.X+d.DX, p.Y+d.DY; x >= 0 && y >= 0 && x < g.Width && y < g.Height; x, y = x+d.DX, y+d.DY {
+		if g.At(x, y) >= h {
+			return false
+		}
+	}
+
+	return true
+}
+
+const example = `30373
+25512
+65332
+33549
+35390
+`
+
+func testGrids(t *testing.T) map[string]*Grid {
+	g, err := Parse(strings.NewReader(example), FormatDigits)
+	if err != nil {
+		t.Fatalf("failed to parse example: %v", err)
+	}
+
+	return map[string]*Grid{
+		"example":  g,
+		"random":   randomGrid(23, 17, 9, 1),
+		"tall":     randomGrid(40, 40, 1000, 2),
+		"ridge":    ridgeGrid(15, 12),
+		"equal":    flatGrid(6, 4, 5),
+		"1x1":      randomGrid(1, 1, 9, 3),
+		"1x9":      randomGrid(1, 9, 9, 4),
+		"9x1":      randomGrid(9, 1, 9, 5),
+		"1x9 flat": flatGrid(1, 9, 3),
+		"9x1 flat": flatGrid(9, 1, 3),
+	}
+}
+
+func TestAnalyzeDirections(t *testing.T) {
+	dirSets := []string{"orthogonal", "diagonal", "eight", "2,1", "2,1;-3,2", "0,-3;1,1"}
+
+	for name, g := range testGrids(t) {
+		for _, set := range dirSets {
+			dirs, err := ParseDirections(set)
+			if err != nil {
+				t.Fatalf("failed to parse directions %q: %v", set, err)
+			}
+
+			t.Run(name+"/"+set, func(t *testing.T) {
+				a, err := AnalyzeDirections(g, dirs)
+				if err != nil {
+					t.Fatalf("failed to analyze: %v", err)
+				}
+
+				for y := 0; y < g.Height; y++ {
+					for x := 0; x < g.Width; x++ {
+						p := Point{X: x, Y: y}
+						i := y*g.Width + x
+
+						for n, d := range dirs {
+							want := visibleFrom(g, p, d)
+
+							if got := a.Visible[i]&(1<<n) != 0; got != want {
+								t.Errorf("%s visible from %s: got %v, want %v",
+									p, d, got, want)
+							}
+						}
+
+						if want := ScenicScore(g, p, dirs); a.Scores[i] != want {
+							t.Errorf("%s score: got %d, want %d",
+								p, a.Scores[i], want)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestAnalyzeExample(t *testing.T) {
+	a, err := Analyze(testGrids(t)["example"])
+	if err != nil {
+		t.Fatalf("failed to analyze: %v", err)
+	}
+
+	if got := a.VisibleCount(); got != 21 {
+		t.Errorf("got %d visible trees, want 21", got)
+	}
+
+	if x, y, score := a.Best(); x != 2 || y != 3 || score != 8 {
+		t.Errorf("got best score %d at %d,%d, want 8 at 2,3", score, x, y)
+	}
+}
+
+// benchGrids runs fn on random and ridge shaped grids of the given
+// sizes. Grids over 1000x1000 are skipped with -short.
+func benchGrids(b *testing.B, sizes []int, fn func(b *testing.B, g *Grid)) {
+	for _, size := range sizes {
+		if size > 1000 && testing.Short() {
+			continue
+		}
+
+		grids := []struct {
+			name string
+			g    *Grid
+		}{
+			{"random", randomGrid(size, size, 9, 1)},
+			{"ridge", ridgeGrid(size, size)},
+		}
+
+		for _, bg := range grids {
+			g := bg.g
+
+			b.Run(fmt.Sprintf("%s/%dx%d", bg.name, size, size), func(b *testing.B) {
+				fn(b, g)
+			})
+		}
+	}
+}
+
+// reportTrees runs fn b.N times and reports how many trees of g were
+// handled per second.
+func reportTrees(b *testing.B, g *Grid, fn func() error) {
+	b.ResetTimer()
+
+	start := time.Now()
+
+	for i := 0; i < b.N; i++ {
+		if err := fn(); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	elapsed := time.Since(start)
+
+	b.ReportMetric(float64(b.N)*float64(g.Width*g.Height)/elapsed.Seconds(), "trees/s")
+}
+
+func BenchmarkAnalyze(b *testing.B) {
+	benchGrids(b, []int{100, 1000, 5000}, func(b *testing.B, g *Grid) {
+		reportTrees(b, g, func() error {
+			_, err := Analyze(g)
+			return err
+		})
+	})
+}
+
+// BenchmarkAnalyzeEightWay stays below the grid sizes where eight way
+// scores could overflow.
+func BenchmarkAnalyzeEightWay(b *testing.B) {
+	benchGrids(b, []int{100, 400}, func(b *testing.B, g *Grid) {
+		reportTrees(b, g, func() error {
+			_, err := AnalyzeDirections(g, EightWay)
+			return err
+		})
+	})
+}
@@ -0,0 +1,148 @@
+package forest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Direction is a line of sight, the step from a tree to the next tree
+// that it looks at. Steps longer than one tree look past the trees in
+// between, the way a ray cast through the grid would.
+type Direction struct {
+	DX, DY int
+}
+
+// MaxDirections is the number of directions that fit in a Visibility.
+const MaxDirections = 32
+
+// The direction sets that can be referred to by name. Orthogonal matches
+// the Visible constants, the first four of EightWay do as well.
+var (
+	Orthogonal = []Direction{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+	Diagonal   = []Direction{{1, -1}, {1, 1}, {-1, 1}, {-1, -1}}
+	EightWay   = append(append([]Direction{}, Orthogonal...), Diagonal...)
+)
+
+var directionSets = map[string][]Direction{
+	"orthogonal": Orthogonal,
+	"diagonal":   Diagonal,
+	"eight":      EightWay,
+}
+
+var directionNames = map[Direction]string{
+	{0, -1}:  "top",
+	{1, -1}:  "top-right",
+	{1, 0}:   "right",
+	{1, 1}:   "bottom-right",
+	{0, 1}:   "bottom",
+	{-1, 1}:  "bottom-left",
+	{-1, 0}:  "left",
+	{-1, -1}: "top-left",
+}
+
+// String returns the name of the side of the forest that d looks
+// towards, like "top-left", or the step as "dx,dy" if it isn't one of
+// the eight neighbours.
+func (d Direction) String() string {
+	if name, ok := directionNames[d]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("%d,%d", d.DX, d.DY)
+}
+
+// ParseDirections parses a direction set: orthogonal, diagonal, eight,
+// or a list of steps written as "dx,dy" and separated by semicolons or
+// spaces, where y grows downwards.
+func ParseDirections(s string) ([]Direction, error) {
+	if set, ok := directionSets[s]; ok {
+		return set, nil
+	}
+
+	steps := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ';' || r == ' ' || r == '\t'
+	})
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("no directions in %q", s)
+	}
+
+	dirs := make([]Direction, 0, len(steps))
+
+	for _, step := range steps {
+		dx, dy, ok := strings.Cut(step, ",")
+		if !ok {
+			return nil, fmt.Errorf("invalid direction %q, expected dx,dy", step)
+		}
+
+		x, errX := strconv.Atoi(dx)
+		y, errY := strconv.Atoi(dy)
+
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("invalid direction %q, expected dx,dy", step)
+		}
+
+		dirs = append(dirs, Direction{DX: x, DY: y})
+	}
+
+	if err := checkDirections(dirs); err != nil {
+		return nil, err
+	}
+
+	return dirs, nil
+}
+
+func checkDirections(dirs []Direction) error {
+	if len(dirs) == 0 {
+		return fmt.Errorf("no directions to look in")
+	}
+
+	if len(dirs) > MaxDirections {
+		return fmt.Errorf("too many directions, at most %d are supported", MaxDirections)
+	}
+
+	seen := make(map[Direction]bool, len(dirs))
+
+	for _, d := range dirs {
+		if d == (Direction{}) {
+			return fmt.Errorf("direction 0,0 doesn't look anywhere")
+		}
+
+		if seen[d] {
+			return fmt.Errorf("direction %d,%d is given more than once", d.DX, d.DY)
+		}
+
+		seen[d] = true
+	}
+
+	return nil
+}
+
+// steps returns the number of steps in direction d that stay within a
+// grid of the given size, starting at x, y.
+func (d Direction) steps(x, y, width, height int) int {
+	steps := -1
+
+	limit := func(n int) {
+		if steps == -1 || n < steps {
+			steps = n
+		}
+	}
+
+	switch {
+	case d.DX > 0:
+		limit((width - 1 - x) / d.DX)
+	case d.DX < 0:
+		limit(x / -d.DX)
+	}
+
+	switch {
+	case d.DY > 0:
+		limit((height - 1 - y) / d.DY)
+	case d.DY < 0:
+		limit(y / -d.DY)
+	}
+
+	return steps
+}
@@ -0,0 +1,251 @@
+// Package forest analyses tree height maps, working out which trees can
+// be seen from outside the forest and how scenic the view from each tree
+// is.
+package forest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Grid is a rectangular map of tree heights, stored row by row.
+type Grid struct {
+	Width   int
+	Height  int
+	Heights []int
+}
+
+// NewGrid creates a grid of the given size where every tree has a height
+// of zero.
+func NewGrid(width, height int) *Grid {
+	return &Grid{
+		Width:   width,
+		Height:  height,
+		Heights: make([]int, width*height),
+	}
+}
+
+// At returns the height of the tree at x, y.
+func (g *Grid) At(x, y int) int {
+	return g.Heights[y*g.Width+x]
+}
+
+// Format is the way heights are written in a grid.
+type Format int
+
+const (
+	// FormatAuto picks FormatSeparated if every row contains a comma
+	// or whitespace between heights, and FormatDigits otherwise, so a
+	// stray separator in a digit grid is reported as an invalid cell.
+	// A grid that is a single column of separated heights has no
+	// separators, so it has to be read with FormatSeparated explicitly.
+	FormatAuto Format = iota
+	// FormatDigits has one digit per tree, like the puzzle input.
+	FormatDigits
+	// FormatSeparated has integer heights separated by commas or
+	// whitespace.
+	FormatSeparated
+)
+
+var formatNames = map[string]Format{
+	"auto":      FormatAuto,
+	"digits":    FormatDigits,
+	"separated": FormatSeparated,
+}
+
+// ParseFormat parses a format name: auto, digits or separated.
+func ParseFormat(s string) (Format, error) {
+	f, ok := formatNames[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown grid format %q, use auto, digits or separated", s)
+	}
+
+	return f, nil
+}
+
+// CellError reports a problem with a cell in a grid. Row and Column are
+// both 1-based, Column counts trees for separated grids and bytes for
+// digit grids.
+type CellError struct {
+	Row    int
+	Column int
+	Msg    string
+}
+
+func (e *CellError) Error() string {
+	return fmt.Sprintf("row %d, column %d: %s", e.Row, e.Column, e.Msg)
+}
+
+// CellErrors are all the problems found in a grid, in reading order.
+type CellErrors []*CellError
+
+// maxReported is the number of problems that CellErrors.Error lists.
+const maxReported = 20
+
+func (e CellErrors) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d invalid cells in the grid", len(e))
+
+	for i, c := range e {
+		if i == maxReported {
+			fmt.Fprintf(&b, "\n  and %d more", len(e)-maxReported)
+			break
+		}
+
+		b.WriteString("\n  ")
+		b.WriteString(c.Error())
+	}
+
+	return b.String()
+}
+
+func cellErr(row, col int, format string, a ...any) *CellError {
+	return &CellError{
+		Row:    row,
+		Column: col,
+		Msg:    fmt.Sprintf(format, a...),
+	}
+}
+
+// maxLine is the longest row that can be read, wide separated grids
+// easily outgrow the default bufio.Scanner limit.
+const maxLine = 256 << 20
+
+// Parse reads a grid with one row per line, in the format given by f.
+// Blank lines at the end of the grid are ignored. Every invalid cell is
+// reported, as CellErrors, rather than just the first one.
+func Parse(r io.Reader, f Format) (*Grid, error) {
+	var (
+		rows      [][]byte
+		blanks    []int
+		separated = true
+	)
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), maxLine)
+
+	for s.Scan() {
+		line := bytes.TrimRight(s.Bytes(), "\r")
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			blanks = append(blanks, len(rows))
+			continue
+		}
+
+		rows = append(rows, append([]byte(nil), line...))
+
+		if !bytes.ContainsAny(bytes.TrimSpace(line), ", \t") {
+			separated = false
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read grid: %w", err)
+	}
+
+	if f == FormatAuto {
+		f = FormatDigits
+
+		if separated && len(rows) > 0 {
+			f = FormatSeparated
+		}
+	}
+
+	var (
+		g    Grid
+		errs CellErrors
+	)
+
+	// Blank lines are recorded by the number of rows above them, so
+	// the ones followed by a row are inside the grid.
+	for _, above := range blanks {
+		if above < len(rows) {
+			errs = append(errs, cellErr(above+1, 1, "unexpected blank line above the row"))
+		}
+	}
+
+	for i, line := range rows {
+		row := i + 1
+
+		var n int
+
+		if f == FormatSeparated {
+			g.Heights, n = appendSeparated(g.Heights, line, row, &errs)
+		} else {
+			g.Heights, n = appendDigits(g.Heights, line, row, &errs)
+		}
+
+		if row > 1 && n != g.Width {
+			errs = append(errs, cellErr(row, 1,
+				"uneven grid, the row has %d trees but the first row has %d",
+				n, g.Width))
+
+			continue
+		}
+
+		g.Width = n
+		g.Height++
+	}
+
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool {
+			a, b := errs[i], errs[j]
+
+			return a.Row < b.Row || a.Row == b.Row && a.Column < b.Column
+		})
+
+		return nil, errs
+	}
+
+	return &g, nil
+}
+
+func appendDigits(heights []int, line []byte, row int, errs *CellErrors) ([]int, int) {
+	for i, b := range line {
+		if b < '0' || b > '9' {
+			*errs = append(*errs, cellErr(row, i+1, "invalid height %q", b))
+		}
+
+		heights = append(heights, int(b-'0'))
+	}
+
+	return heights, len(line)
+}
+
+// appendSeparated reads heights separated by commas, or by whitespace if
+// the line has no commas.
+func appendSeparated(heights []int, line []byte, row int, errs *CellErrors) ([]int, int) {
+	var fields [][]byte
+
+	if bytes.IndexByte(line, ',') != -1 {
+		fields = bytes.Split(line, []byte{','})
+	} else {
+		fields = bytes.Fields(line)
+	}
+
+	for i, field := range fields {
+		field = bytes.TrimSpace(field)
+
+		h, err := strconv.Atoi(string(field))
+
+		switch {
+		case len(field) == 0:
+			*errs = append(*errs, cellErr(row, i+1, "missing height"))
+		case errors.Is(err, strconv.ErrRange):
+			*errs = append(*errs, cellErr(row, i+1, "height %s is out of range", field))
+		case err != nil:
+			*errs = append(*errs, cellErr(row, i+1, "invalid height %q", field))
+		}
+
+		heights = append(heights, h)
+	}
+
+	return heights, len(fields)
+}
@@ -0,0 +1,75 @@
+package forest
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAuto(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		heights []int
+		errs    []CellError
+	}{
+		{
+			name:    "digits",
+			input:   "303\n255\n653\n",
+			heights: []int{3, 0, 3, 2, 5, 5, 6, 5, 3},
+		},
+		{
+			name:    "separated",
+			input:   "3,0,3\n2 5 5\n6\t5\t3\n",
+			heights: []int{3, 0, 3, 2, 5, 5, 6, 5, 3},
+		},
+		{
+			name:    "multi-digit heights",
+			input:   "10,2\n3,40\n",
+			heights: []int{10, 2, 3, 40},
+		},
+		{
+			name:  "stray space in digits",
+			input: "303\n2 5\n653\n",
+			errs:  []CellError{{Row: 2, Column: 2, Msg: "invalid height ' '"}},
+		},
+		{
+			name:  "stray comma in digits",
+			input: "3,3\n255\n653\n",
+			errs:  []CellError{{Row: 1, Column: 2, Msg: "invalid height ','"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Parse(strings.NewReader(tt.input), FormatAuto)
+
+			if tt.errs != nil {
+				var cells CellErrors
+				if !errors.As(err, &cells) {
+					t.Fatalf("expected cell errors, got %v", err)
+				}
+
+				got := make([]CellError, len(cells))
+				for i, c := range cells {
+					got[i] = *c
+				}
+
+				if !reflect.DeepEqual(got, tt.errs) {
+					t.Errorf("got errors %+v, want %+v", got, tt.errs)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("failed to parse grid: %v", err)
+			}
+
+			if !reflect.DeepEqual(g.Heights, tt.heights) {
+				t.Errorf("got heights %v, want %v", g.Heights, tt.heights)
+			}
+		})
+	}
+}
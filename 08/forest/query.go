@@ -0,0 +1,121 @@
+package forest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Point is a position in the grid. X is the column and Y the row,
+// counting from zero at the top left tree.
+type Point struct {
+	X, Y int
+}
+
+func (p Point) String() string {
+	return fmt.Sprintf("%d,%d", p.X, p.Y)
+}
+
+// ParsePoint parses a point written as "x,y" or "x y".
+func ParsePoint(s string) (Point, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+
+	if len(fields) != 2 {
+		return Point{}, fmt.Errorf("invalid point %q, expected x,y", s)
+	}
+
+	x, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid x coordinate %q", fields[0])
+	}
+
+	y, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid y coordinate %q", fields[1])
+	}
+
+	return Point{X: x, Y: y}, nil
+}
+
+// Tree describes a single tree in an analysed grid.
+type Tree struct {
+	Pos         Point
+	Height      int
+	Visible     Visibility
+	VisibleFrom []Direction
+	// Distances are the viewing distances in each of the directions of
+	// the analysis.
+	Distances []int
+	Score     int
+}
+
+// Tree returns the details of the tree at p. The viewing distances
+// aren't kept by Analyze, so they are worked out again by looking out
+// from the tree.
+func (a *Analysis) Tree(p Point) (Tree, error) {
+	g := a.grid
+
+	if p.X < 0 || p.Y < 0 || p.X >= g.Width || p.Y >= g.Height {
+		return Tree{}, fmt.Errorf("%s is outside of the %dx%d forest",
+			p, g.Width, g.Height)
+	}
+
+	i := p.Y*g.Width + p.X
+
+	t := Tree{
+		Pos:         p,
+		Height:      g.Heights[i],
+		Visible:     a.Visible[i],
+		VisibleFrom: a.VisibleFrom(a.Visible[i]),
+		Distances:   make([]int, len(a.Directions)),
+		Score:       a.Scores[i],
+	}
+
+	for n, d := range a.Directions {
+		t.Distances[n] = viewDistance(g, p, d)
+	}
+
+	return t, nil
+}
+
+// Trees looks up the trees at the points in r, one point per line.
+// Blank lines and lines starting with # are ignored.
+func (a *Analysis) Trees(r io.Reader) ([]Tree, error) {
+	var (
+		trees []Tree
+		linum int
+	)
+
+	s := bufio.NewScanner(r)
+
+	for s.Scan() {
+		linum++
+
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p, err := ParsePoint(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w on line %d", err, linum)
+		}
+
+		t, err := a.Tree(p)
+		if err != nil {
+			return nil, fmt.Errorf("%w on line %d", err, linum)
+		}
+
+		trees = append(trees, t)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read queries: %w", err)
+	}
+
+	return trees, nil
+}
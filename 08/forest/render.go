@@ -0,0 +1,252 @@
+package forest
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Palette is a colour gradient, from the colour used for the lowest
+// value to the one used for the highest.
+type Palette struct {
+	Stops []color.RGBA
+	// Marker is a colour that stands out against the gradient, used to
+	// highlight trees.
+	Marker color.RGBA
+}
+
+// Palettes are the named palettes that can be used for rendering.
+var Palettes = map[string]Palette{
+	"forest": {
+		Stops: []color.RGBA{
+			{R: 0x10, G: 0x1c, B: 0x0c, A: 0xff},
+			{R: 0x2e, G: 0x6b, B: 0x1f, A: 0xff},
+			{R: 0xb8, G: 0xe0, B: 0x7a, A: 0xff},
+		},
+		Marker: color.RGBA{R: 0xff, G: 0x30, B: 0x30, A: 0xff},
+	},
+	"gray": {
+		Stops: []color.RGBA{
+			{A: 0xff},
+			{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+		},
+		Marker: color.RGBA{R: 0xff, G: 0x30, B: 0x30, A: 0xff},
+	},
+	"heat": {
+		Stops: []color.RGBA{
+			{A: 0xff},
+			{R: 0xb0, A: 0xff},
+			{R: 0xff, G: 0x90, A: 0xff},
+			{R: 0xff, G: 0xff, B: 0xe0, A: 0xff},
+		},
+		Marker: color.RGBA{R: 0x30, G: 0xd0, B: 0xff, A: 0xff},
+	},
+}
+
+// PaletteNames returns the names of the palettes in Palettes, sorted.
+func PaletteNames() []string {
+	names := make([]string, 0, len(Palettes))
+
+	for name := range Palettes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// LookupPalette returns the named palette.
+func LookupPalette(name string) (Palette, error) {
+	p, ok := Palettes[name]
+	if !ok {
+		return Palette{}, fmt.Errorf("unknown palette %q, use one of %s",
+			name, strings.Join(PaletteNames(), ", "))
+	}
+
+	return p, nil
+}
+
+// At returns the colour for f, which is clamped to the range 0-1.
+func (p Palette) At(f float64) color.RGBA {
+	switch {
+	case len(p.Stops) == 0:
+		return color.RGBA{A: 0xff}
+	case len(p.Stops) == 1 || f <= 0 || math.IsNaN(f):
+		return p.Stops[0]
+	case f >= 1:
+		return p.Stops[len(p.Stops)-1]
+	}
+
+	pos := f * float64(len(p.Stops)-1)
+	i := int(pos)
+	t := pos - float64(i)
+
+	a, b := p.Stops[i], p.Stops[i+1]
+
+	mix := func(x, y uint8) uint8 {
+		return uint8(math.Round(float64(x) + t*(float64(y)-float64(x))))
+	}
+
+	return color.RGBA{
+		R: mix(a.R, b.R),
+		G: mix(a.G, b.G),
+		B: mix(a.B, b.B),
+		A: mix(a.A, b.A),
+	}
+}
+
+// RenderOptions control how forests are rendered. Every tree is drawn as
+// a square of Scale by Scale pixels.
+type RenderOptions struct {
+	Scale   int
+	Palette Palette
+}
+
+func (o RenderOptions) scale() int {
+	if o.Scale < 1 {
+		return 1
+	}
+
+	return o.Scale
+}
+
+// RenderVisibility draws every tree shaded by its height, with a mark in
+// the marker colour on each side of the tree that it's visible from.
+// Orthogonal directions are marked with a bar along that side of the
+// tree and other directions with a square in the corner they point to.
+// The marks are a quarter of a tree wide, so at scales below four a
+// visible tree is drawn entirely in the marker colour.
+func RenderVisibility(a *Analysis, opts RenderOptions) *image.RGBA {
+	g := a.grid
+	s := opts.scale()
+	img := image.NewRGBA(image.Rect(0, 0, g.Width*s, g.Height*s))
+
+	low, high := heightRange(g)
+	bar := s / 4
+
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			i := y*g.Width + x
+			cell := image.Rect(x*s, y*s, (x+1)*s, (y+1)*s)
+
+			fill(img, cell, opts.Palette.At(normalize(g.Heights[i], low, high)))
+
+			v := a.Visible[i]
+			if v == Hidden {
+				continue
+			}
+
+			if bar == 0 {
+				fill(img, cell, opts.Palette.Marker)
+				continue
+			}
+
+			for _, d := range a.VisibleFrom(v) {
+				fill(img, mark(cell, d, bar), opts.Palette.Marker)
+			}
+		}
+	}
+
+	return img
+}
+
+// mark returns the part of the cell to mark for direction d.
+func mark(cell image.Rectangle, d Direction, bar int) image.Rectangle {
+	r := cell
+
+	switch {
+	case d.DX < 0:
+		r.Max.X = r.Min.X + bar
+	case d.DX > 0:
+		r.Min.X = r.Max.X - bar
+	}
+
+	switch {
+	case d.DY < 0:
+		r.Max.Y = r.Min.Y + bar
+	case d.DY > 0:
+		r.Min.Y = r.Max.Y - bar
+	}
+
+	return r
+}
+
+// RenderScores draws a heat map of the scenic scores. Scores are spread
+// over the palette on a log scale, as a handful of trees usually score
+// orders of magnitude higher than the rest. The best spot for a
+// treehouse is framed in the marker colour.
+func RenderScores(a *Analysis, opts RenderOptions) *image.RGBA {
+	s := opts.scale()
+	img := image.NewRGBA(image.Rect(0, 0, a.Width*s, a.Height*s))
+
+	bx, by, best := a.Best()
+	top := math.Log1p(float64(best))
+
+	for y := 0; y < a.Height; y++ {
+		for x := 0; x < a.Width; x++ {
+			var f float64
+
+			if top > 0 {
+				f = math.Log1p(float64(a.Scores[y*a.Width+x])) / top
+			}
+
+			fill(img, image.Rect(x*s, y*s, (x+1)*s, (y+1)*s), opts.Palette.At(f))
+		}
+	}
+
+	if a.Width == 0 || a.Height == 0 {
+		return img
+	}
+
+	// Frame the tree with a one tree wide border, so that it can be
+	// spotted even at a scale of one.
+	frame := image.Rect((bx-1)*s, (by-1)*s, (bx+2)*s, (by+2)*s)
+	width := s/2 + 1
+
+	outline(img, frame, width, opts.Palette.Marker)
+
+	return img
+}
+
+func heightRange(g *Grid) (low, high int) {
+	for i, h := range g.Heights {
+		if i == 0 || h < low {
+			low = h
+		}
+
+		if i == 0 || h > high {
+			high = h
+		}
+	}
+
+	return low, high
+}
+
+func normalize(v, low, high int) float64 {
+	if high == low {
+		return 1
+	}
+
+	return float64(v-low) / float64(high-low)
+}
+
+func fill(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	r = r.Intersect(img.Bounds())
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+func outline(img *image.RGBA, r image.Rectangle, width int, c color.RGBA) {
+	fill(img, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+width), c)
+	fill(img, image.Rect(r.Min.X, r.Max.Y-width, r.Max.X, r.Max.Y), c)
+	fill(img, image.Rect(r.Min.X, r.Min.Y, r.Min.X+width, r.Max.Y), c)
+	fill(img, image.Rect(r.Max.X-width, r.Min.Y, r.Max.X, r.Max.Y), c)
+}
@@ -0,0 +1,141 @@
+package forest
+
+import (
+	"runtime"
+	"sync"
+)
+
+// ScenicScore works out the scenic score of the tree at p by looking out
+// from it in each direction until the view is blocked. It doesn't need
+// an Analysis, but takes O(width+height) time per direction. Scores
+// that are too large for an int saturate at math.MaxInt.
+func ScenicScore(g *Grid, p Point, dirs []Direction) int {
+	score := 1
+
+	for _, d := range dirs {
+		score = mulScore(score, viewDistance(g, p, d))
+	}
+
+	return score
+}
+
+// viewDistance counts the trees that can be seen looking out from p in
+// direction d, up to and including the first one that is at least as
+// tall as the tree at p.
+func viewDistance(g *Grid, p Point, d Direction) int {
+	var (
+		height   = g.At(p.X, p.Y)
+		distance int
+	)
+
+	for x, y := p.X+d.DX, p.Y+d.DY; x >= 0 && y >= 0 && x < g.Width && y < g.Height; x, y = x+d.DX, y+d.DY {
+		distance++
+
+		if height <= g.At(x, y) {
+			break
+		}
+	}
+
+	return distance
+}
+
+// BestScenicScore returns the tree with the highest ScenicScore, ties
+// going to the first tree in row order. The rows are shared out between
+// the given number of workers, or one per CPU if workers is less than
+// one. The result is the same whatever the number of workers. Like
+// AnalyzeDirections, an error is returned if the scores could get too
+// large for an int.
+func BestScenicScore(g *Grid, dirs []Direction, workers int) (Point, int, error) {
+	if err := checkDirections(dirs); err != nil {
+		return Point{}, 0, err
+	}
+
+	if err := checkScoreRange(g, dirs); err != nil {
+		return Point{}, 0, err
+	}
+
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	if workers > g.Height {
+		workers = g.Height
+	}
+
+	if workers <= 1 {
+		best := bestInRows(g, dirs, 0, g.Height, 1)
+
+		return best.point(g), best.score, nil
+	}
+
+	results := make([]candidate, workers)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		// Interleave the rows, so that no worker gets stuck with a
+		// slow band of the forest.
+		go func(w int) {
+			defer wg.Done()
+
+			results[w] = bestInRows(g, dirs, w, g.Height, workers)
+		}(w)
+	}
+
+	wg.Wait()
+
+	best := results[0]
+
+	for _, c := range results[1:] {
+		if c.beats(best) {
+			best = c
+		}
+	}
+
+	return best.point(g), best.score, nil
+}
+
+// candidate is the best tree found by a worker.
+type candidate struct {
+	index int
+	score int
+}
+
+func (c candidate) beats(o candidate) bool {
+	if o.index == -1 {
+		return c.index != -1
+	}
+
+	return c.index != -1 &&
+		(c.score > o.score || c.score == o.score && c.index < o.index)
+}
+
+func (c candidate) point(g *Grid) Point {
+	if c.index == -1 {
+		return Point{}
+	}
+
+	return Point{X: c.index % g.Width, Y: c.index / g.Width}
+}
+
+// bestInRows scores every step:th row from first up to end.
+func bestInRows(g *Grid, dirs []Direction, first, end, step int) candidate {
+	best := candidate{index: -1}
+
+	for y := first; y < end; y += step {
+		for x := 0; x < g.Width; x++ {
+			c := candidate{
+				index: y*g.Width + x,
+				score: ScenicScore(g, Point{X: x, Y: y}, dirs),
+			}
+
+			if c.beats(best) {
+				best = c
+			}
+		}
+	}
+
+	return best
+}
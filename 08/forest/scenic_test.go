@@ -0,0 +1,62 @@
+package forest
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+func TestBestScenicScore(t *testing.T) {
+	grids := map[string]*Grid{
+		"random":   randomGrid(31, 23, 9, 1),
+		"tall":     randomGrid(17, 29, 1000, 2),
+		"tied":     flatGrid(12, 9, 4),
+		"two rows": randomGrid(40, 2, 2, 3),
+		"1x1":      flatGrid(1, 1, 0),
+		"1x9":      randomGrid(1, 9, 9, 4),
+		"9x1":      randomGrid(9, 1, 9, 5),
+		"ridge":    ridgeGrid(11, 11),
+	}
+
+	for name, g := range grids {
+		for _, dirs := range [][]Direction{Orthogonal, EightWay} {
+			a, err := AnalyzeDirections(g, dirs)
+			if err != nil {
+				t.Fatalf("failed to analyze: %v", err)
+			}
+
+			x, y, want := a.Best()
+
+			for _, w := range []int{0, 1, 2, 3, 7} {
+				p, score, err := BestScenicScore(g, dirs, w)
+				if err != nil {
+					t.Fatalf("%s with %d workers: %v", name, w, err)
+				}
+
+				if p.X != x || p.Y != y || score != want {
+					t.Errorf("%s looking %d ways with %d workers: got score %d at %s, want %d at %d,%d",
+						name, len(dirs), w, score, p, want, x, y)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkBestScenicScore(b *testing.B) {
+	workers := []int{1, 2, 4}
+
+	if cpus := runtime.NumCPU(); cpus > 4 {
+		workers = append(workers, cpus)
+	}
+
+	benchGrids(b, []int{1000, 3000}, func(b *testing.B, g *Grid) {
+		for _, w := range workers {
+			b.Run(fmt.Sprintf("workers=%d", w), func(b *testing.B) {
+				reportTrees(b, g, func() error {
+					_, _, err := BestScenicScore(g, Orthogonal, w)
+					return err
+				})
+			})
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package forest
+
+import "sort"
+
+// SiteOptions control the choice of treehouse sites.
+type SiteOptions struct {
+	// Count is the number of sites to choose.
+	Count int
+	// MinDistance is the shortest straight line distance, in trees,
+	// that is allowed between two sites.
+	MinDistance float64
+	// ExcludeEdges rules out the trees along the edges of the forest.
+	ExcludeEdges bool
+}
+
+// TopSites picks the trees with the highest scenic scores for treehouses.
+// Sites are chosen greedily, best score first, skipping trees that are
+// too close to a site that has already been picked, so fewer than
+// opts.Count sites are returned if the forest runs out of trees. Ties go
+// to the first tree in row order.
+func (a *Analysis) TopSites(opts SiteOptions) []Tree {
+	if opts.Count < 1 {
+		return nil
+	}
+
+	candidates := make([]int, 0, len(a.Scores))
+
+	for i := range a.Scores {
+		x, y := i%a.Width, i/a.Width
+
+		if opts.ExcludeEdges && (x == 0 || y == 0 || x == a.Width-1 || y == a.Height-1) {
+			continue
+		}
+
+		candidates = append(candidates, i)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return a.Scores[candidates[i]] > a.Scores[candidates[j]]
+	})
+
+	minSquared := opts.MinDistance * opts.MinDistance
+
+	var sites []Tree
+
+	for _, i := range candidates {
+		p := Point{X: i % a.Width, Y: i / a.Width}
+
+		if tooClose(sites, p, minSquared) {
+			continue
+		}
+
+		t, _ := a.Tree(p)
+		sites = append(sites, t)
+
+		if len(sites) == opts.Count {
+			break
+		}
+	}
+
+	return sites
+}
+
+func tooClose(sites []Tree, p Point, minSquared float64) bool {
+	for _, s := range sites {
+		dx := float64(s.Pos.X - p.X)
+		dy := float64(s.Pos.Y - p.Y)
+
+		if dx*dx+dy*dy < minSquared {
+			return true
+		}
+	}
+
+	return false
+}
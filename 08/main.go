@@ -1,9 +1,14 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
+	"image"
+	"image/png"
 	"os"
+	"strings"
+
+	"github.com/hugowetterberg/advent2022/08/forest"
 )
 
 func main() {
@@ -13,164 +18,193 @@ func main() {
 	}
 }
 
-const (
-	hidden        = 0
-	visibleTop    = 1
-	visibleRight  = 1 << 1
-	visibleBottom = 1 << 2
-	visibleLeft   = 1 << 3
-)
-
-type Rule struct {
-	Movement [2]int
-	Mask     int
-}
+// points is a repeatable flag for trees to look up.
+type points []forest.Point
 
-var rules = []Rule{
-	{
-		Movement: [2]int{0, 1},
-		Mask:     visibleTop,
-	},
-	{
-		Movement: [2]int{-1, 0},
-		Mask:     visibleRight,
-	},
-	{
-		Movement: [2]int{0, -1},
-		Mask:     visibleBottom,
-	},
-	{
-		Movement: [2]int{1, 0},
-		Mask:     visibleLeft,
-	},
+func (p *points) String() string {
+	return fmt.Sprint(*p)
 }
 
-func start(m [2]int, x, y int) [2]int {
-	var s [2]int
-
-	if m[0] < 0 {
-		s[0] = 1
+func (p *points) Set(v string) error {
+	pt, err := forest.ParsePoint(v)
+	if err != nil {
+		return err
 	}
 
-	if m[1] < 0 {
-		s[1] = 1
-	}
+	*p = append(*p, pt)
 
-	return [2]int{
-		s[0] * x,
-		s[1] * y,
-	}
+	return nil
 }
 
-func next(s, i, m [2]int, x, y int) ([2]int, bool) {
-	n := [2]int{
-		i[0] + m[0],
-		i[1] + m[1],
+func run() error {
+	var (
+		visibilityPNG, scoresPNG, palette string
+		queryFile, formatName, dirNames   string
+		queries                           points
+		scale                             int
+	)
+
+	flag.StringVar(&formatName, "format", "auto",
+		"grid `format`: digits, separated (by commas or whitespace) or auto")
+	flag.StringVar(&dirNames, "directions", "orthogonal",
+		"directions to look in: orthogonal, diagonal, eight, or steps like \"1,0;2,1\"")
+	flag.StringVar(&visibilityPNG, "visibility-png", "",
+		"write an image of tree heights and the sides they are visible from to `file`")
+	flag.StringVar(&scoresPNG, "scores-png", "",
+		"write a heat map of the scenic scores to `file`")
+	flag.IntVar(&scale, "scale", 4, "size of each tree in pixels")
+	flag.StringVar(&palette, "palette", "",
+		"palette for the images, one of "+strings.Join(forest.PaletteNames(), ", ")+
+			" (default forest for visibility and heat for scores)")
+	flag.Var(&queries, "query",
+		"print the details of the tree at `x,y`, can be repeated")
+	flag.StringVar(&queryFile, "queries", "",
+		"print the details of the trees listed in `file`, one x,y per line")
+	flag.Parse()
+
+	if scale < 1 {
+		return fmt.Errorf("scale must be at least 1")
 	}
 
-	if m[0] != 0 && (n[0] < 0 || n[0] > x) {
-		return shift(s, m, i), true
+	format, err := forest.ParseFormat(formatName)
+	if err != nil {
+		return err
 	}
 
-	if m[1] != 0 && (n[1] < 0 || n[1] > y) {
-		return shift(s, m, i), true
+	dirs, err := forest.ParseDirections(dirNames)
+	if err != nil {
+		return err
 	}
 
-	return n, false
-}
-
-func atEnd(m, i [2]int, x, y int) bool {
-	if m[0] == 0 && (i[0] < 0 || i[0] > x) {
-		return true
+	grid, err := forest.Parse(os.Stdin, format)
+	if err != nil {
+		return err
 	}
 
-	if m[1] == 0 && (i[1] < 0 || i[1] > y) {
-		return true
+	a, err := forest.AnalyzeDirections(grid, dirs)
+	if err != nil {
+		return err
 	}
 
-	return false
-}
+	println("visible", a.VisibleCount())
 
-func shift(s [2]int, m [2]int, i [2]int) [2]int {
-	if m[0] == 0 {
-		return [2]int{i[0] + 1, s[1]}
-	}
-
-	return [2]int{s[0], i[1] + 1}
-}
+	_, _, score := a.Best()
 
-func run() error {
-	var linum, colnum int
+	println("score", score)
 
-	treeMap := [][]int{}
-	visibility := [][]int{}
+	for _, p := range queries {
+		t, err := a.Tree(p)
+		if err != nil {
+			return err
+		}
 
-	r := bufio.NewScanner(os.Stdin)
+		printTree(a, t)
+	}
 
-	for r.Scan() {
-		line := r.Bytes()
-		linum++
+	if queryFile != "" {
+		if err := queryTrees(a, queryFile); err != nil {
+			return err
+		}
+	}
 
-		if colnum != 0 && len(line) != colnum {
-			return fmt.Errorf("uneven grid on line %d", linum)
+	if visibilityPNG != "" {
+		opts, err := renderOptions(palette, "forest", scale)
+		if err != nil {
+			return err
 		}
 
-		colnum = len(line)
+		err = writePNG(visibilityPNG, forest.RenderVisibility(a, opts))
+		if err != nil {
+			return err
+		}
+	}
 
-		row := make([]int, colnum)
+	if scoresPNG != "" {
+		opts, err := renderOptions(palette, "heat", scale)
+		if err != nil {
+			return err
+		}
 
-		for i := 0; i < colnum; i++ {
-			row[i] = int(line[i] - 48)
+		err = writePNG(scoresPNG, forest.RenderScores(a, opts))
+		if err != nil {
+			return err
 		}
+	}
 
-		treeMap = append(treeMap, row)
+	return nil
+}
 
-		vis := make([]int, colnum)
-		visibility = append(visibility, vis)
+func queryTrees(a *forest.Analysis, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open queries: %w", err)
 	}
 
-	if err := r.Err(); err != nil {
-		return fmt.Errorf("failed to read stdin: %w", err)
+	defer f.Close()
+
+	trees, err := a.Trees(f)
+	if err != nil {
+		return err
 	}
 
-	for i := range rules {
-		applyRule(rules[i], treeMap, visibility)
+	for _, t := range trees {
+		printTree(a, t)
 	}
 
-	var visible int
+	return nil
+}
 
-	for i := 0; i < linum*colnum; i++ {
-		y := i / colnum
-		x := i % colnum
+func printTree(a *forest.Analysis, t forest.Tree) {
+	visible := "hidden"
 
-		if visibility[y][x] > 0 {
-			visible++
+	if len(t.VisibleFrom) > 0 {
+		names := make([]string, len(t.VisibleFrom))
+
+		for i, d := range t.VisibleFrom {
+			names[i] = d.String()
 		}
+
+		visible = strings.Join(names, ",")
 	}
 
-	println("visible", visible)
+	var distances strings.Builder
 
-	return nil
+	for i, d := range a.Directions {
+		fmt.Fprintf(&distances, " %s %d", d, t.Distances[i])
+	}
+
+	fmt.Fprintf(os.Stdout, "tree %s height %d visible %s distances%s score %d\n",
+		t.Pos, t.Height, visible, distances.String(), t.Score)
 }
 
-func applyRule(r Rule, m, v [][]int) {
-	x := len(m[0]) - 1
-	y := len(m) - 1
-	s := start(r.Movement, x, y)
-	height := -1
+func renderOptions(palette, fallback string, scale int) (forest.RenderOptions, error) {
+	if palette == "" {
+		palette = fallback
+	}
 
-	var shifted bool
+	p, err := forest.LookupPalette(palette)
+	if err != nil {
+		return forest.RenderOptions{}, err
+	}
 
-	for i := s; !atEnd(r.Movement, i, x, y); i, shifted = next(s, i, r.Movement, x, y) {
-		current := m[i[1]][i[0]]
+	return forest.RenderOptions{Scale: scale, Palette: p}, nil
+}
 
-		if shifted {
-			height = -1
-		}
+func writePNG(name string, img image.Image) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create image: %w", err)
+	}
 
-		if current > height {
-			v[i[1]][i[0]] |= r.Mask
-			height = current
-		}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+
+		return fmt.Errorf("failed to write image: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write image: %w", err)
 	}
+
+	return nil
 }
@@ -1,9 +1,12 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"os"
+	"strings"
+
+	"github.com/hugowetterberg/advent2022/08/forest"
 )
 
 func main() {
@@ -14,98 +17,71 @@ func main() {
 }
 
 func run() error {
-	var linum, colnum int
-
-	treeMap := [][]byte{}
-
-	r := bufio.NewScanner(os.Stdin)
-
-	for r.Scan() {
-		line := r.Bytes()
-		linum++
-
-		if colnum != 0 && len(line) != colnum {
-			return fmt.Errorf("uneven grid on line %d", linum)
-		}
-
-		colnum = len(line)
-
-		row := make([]byte, colnum)
-
-		for i := 0; i < colnum; i++ {
-			row[i] = line[i] - 48
-		}
-
-		treeMap = append(treeMap, row)
+	var (
+		formatName, dirNames string
+		sites                forest.SiteOptions
+		workers              int
+	)
+
+	flag.StringVar(&formatName, "format", "auto",
+		"grid `format`: digits, separated (by commas or whitespace) or auto")
+	flag.StringVar(&dirNames, "directions", "orthogonal",
+		"directions to look in: orthogonal, diagonal, eight, or steps like \"1,0;2,1\"")
+	flag.IntVar(&workers, "workers", 1,
+		"number of goroutines to score the rows with, 0 for one per CPU")
+	flag.IntVar(&sites.Count, "top", 0,
+		"list the `k` best treehouse sites instead of just the best score")
+	flag.Float64Var(&sites.MinDistance, "spacing", 0,
+		"minimum `distance` in trees between treehouse sites")
+	flag.BoolVar(&sites.ExcludeEdges, "no-edges", false,
+		"don't place treehouses on the edge of the forest")
+	flag.Parse()
+
+	format, err := forest.ParseFormat(formatName)
+	if err != nil {
+		return err
 	}
 
-	if err := r.Err(); err != nil {
-		return fmt.Errorf("failed to read stdin: %w", err)
+	dirs, err := forest.ParseDirections(dirNames)
+	if err != nil {
+		return err
 	}
 
-	var maxScore int
+	g, err := forest.Parse(os.Stdin, format)
+	if err != nil {
+		return err
+	}
 
-	for i := 0; i < linum*colnum; i++ {
-		y := i / colnum
-		x := i % colnum
+	if sites.Count > 0 {
+		return printSites(g, dirs, sites)
+	}
 
-		score := calculateScenicScore(x, y, treeMap)
-		if score > maxScore {
-			maxScore = score
-		}
+	p, maxScore, err := forest.BestScenicScore(g, dirs, workers)
+	if err != nil {
+		return err
 	}
 
-	println("score", maxScore)
+	println("score", maxScore, "at", p.String())
 
 	return nil
 }
 
-func calculateScenicScore(x, y int, m [][]byte) int {
-	var distances [4]int
-
-	height := m[y][x]
-
-	// Up
-	for i := 1; y >= i; i++ {
-		distances[0] = i
-
-		if height <= m[y-i][x] {
-			break
-		}
-	}
-
-	// Down
-	for i := 1; len(m) > y+i; i++ {
-		distances[1] = i
-
-		if height <= m[y+i][x] {
-			break
-		}
+func printSites(g *forest.Grid, dirs []forest.Direction, opts forest.SiteOptions) error {
+	a, err := forest.AnalyzeDirections(g, dirs)
+	if err != nil {
+		return err
 	}
 
-	// Right
-	for i := 1; len(m[y]) > x+i; i++ {
-		distances[2] = i
+	for n, t := range a.TopSites(opts) {
+		var distances strings.Builder
 
-		if height <= m[y][x+i] {
-			break
+		for i, d := range dirs {
+			fmt.Fprintf(&distances, " %s %d", d, t.Distances[i])
 		}
-	}
-
-	// left
-	for i := 1; x >= i; i++ {
-		distances[3] = i
 
-		if height <= m[y][x-i] {
-			break
-		}
+		fmt.Fprintf(os.Stdout, "%d. %s score %d distances%s\n",
+			n+1, t.Pos, t.Score, distances.String())
 	}
 
-	score := 1
-
-	for _, d := range distances {
-		score *= d
-	}
-
-	return score
+	return nil
 }